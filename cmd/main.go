@@ -2,42 +2,169 @@ package main
 
 import (
 	"context"
-	"log"
+	"database/sql/driver"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/XSAM/otelsql"
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/pressly/goose/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/t5129001t-jpg/subscription-service/docs"
+	"github.com/t5129001t-jpg/subscription-service/internal/authz"
 	"github.com/t5129001t-jpg/subscription-service/internal/config"
 	"github.com/t5129001t-jpg/subscription-service/internal/handler"
+	"github.com/t5129001t-jpg/subscription-service/internal/logging"
+	"github.com/t5129001t-jpg/subscription-service/internal/metrics"
+	"github.com/t5129001t-jpg/subscription-service/internal/middleware"
 	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+	"github.com/t5129001t-jpg/subscription-service/internal/server"
 	"github.com/t5129001t-jpg/subscription-service/internal/service"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
+	"github.com/t5129001t-jpg/subscription-service/internal/tracing"
 )
 
 func main() {
 	cfg := config.LoadConfig()
+	logger := logging.New(os.Stdout, logging.ParseLevel(cfg.Log.Level))
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal("failed to set up tracing", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("failed to flush traces", "error", err)
+		}
+	}()
 
 	db, err := setupDatabase(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("failed to connect to database", err)
 	}
 	defer db.Close()
 
 	if err := runMigrations(cfg); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+		fatal("failed to run migrations", err)
 	}
 
+	prometheus.MustRegister(
+		collectors.NewDBStatsCollector(db.DB, cfg.Database.Name),
+		metrics.NewSubscriptionCollector(repository.NewSubscriptionStatsRepository(db), 5*time.Second),
+	)
+
+	webhookRepo := repository.NewWebhookRepository(db)
+	webhookService := service.NewWebhookService(webhookRepo, cfg.Webhook)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+
 	subscriptionRepo := repository.NewSubscriptionRepository(db)
-	subscriptionService := service.NewSubscriptionService(subscriptionRepo)
+	budgetService := service.NewBudgetService(repository.NewBudgetRepository(db), subscriptionRepo)
+	budgetHandler := handler.NewBudgetHandler(budgetService)
+
+	var authorizer authz.Authorizer
+	if cfg.Auth.Enabled {
+		policy, err := authz.LoadPolicy(cfg.Auth.PolicyFile)
+		if err != nil {
+			fatal("failed to load access policy", err)
+		}
+		authorizer = policy
+	}
+
+	if len(cfg.Erasure.HashKey) < 32 {
+		fatal("invalid erasure hash key", fmt.Errorf("ERASURE_HASH_KEY must be at least 32 bytes"))
+	}
+	userDataRepo := repository.NewUserDataRepository(db, []byte(cfg.Erasure.HashKey))
+	userHandler := handler.NewUserHandler(service.NewUserService(repository.NewUserRepository(db), userDataRepo), authorizer)
+
+	subscriptionService := service.NewTracedSubscriptionService(
+		service.NewSubscriptionService(subscriptionRepo, webhookService, budgetService, authorizer))
 	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionService)
 
-	router := setupRouter(subscriptionHandler)
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	dispatcher := service.NewWebhookDispatcher(webhookRepo, webhookService, subscriptionService, cfg.Webhook)
+	go dispatcher.Run(workerCtx)
+
+	publisher, closePublisher, err := newOutboxPublisher(cfg.Outbox)
+	if err != nil {
+		fatal("failed to set up outbox publisher", err)
+	}
+	defer closePublisher()
+
+	relay := service.NewOutboxRelay(repository.NewOutboxRepository(db), publisher, cfg.Outbox.PollInterval, cfg.Outbox.BatchSize)
+	go relay.Run(workerCtx)
+
+	changeListener, err := repository.NewChangeListener(cfg.Database.GetDBConnString())
+	if err != nil {
+		fatal("failed to listen for subscription changes", err)
+	}
+	defer changeListener.Close()
+
+	changeFeed := service.NewChangeFeed()
+	go changeFeed.Run(workerCtx, changeListener.Changes())
+	streamHandler := handler.NewStreamHandler(changeFeed, authorizer, 15*time.Second)
+
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(db), cfg.Auth.APIKeyRole)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+
+	var authenticate gin.HandlerFunc
+	if cfg.Auth.Enabled {
+		verifier, err := middleware.NewJWTVerifier(cfg.Auth)
+		if err != nil {
+			fatal("failed to set up authentication", err)
+		}
+		authenticate = middleware.Authenticate(verifier, apiKeyService)
+	} else {
+		slog.Warn("authentication is disabled")
+	}
+
+	if !tenant.IsValidID(cfg.Tenant.Default) {
+		fatal("invalid default tenant", fmt.Errorf("%q is not a valid tenant id", cfg.Tenant.Default))
+	}
+	resolveTenant := middleware.ResolveTenant(cfg.Tenant)
+
+	var rateLimiter *middleware.RateLimiter
+	if cfg.RateLimit.Enabled {
+		store, err := service.NewRateLimitStore(cfg.RateLimit.Store, repository.NewRateLimitRepository(db))
+		if err != nil {
+			fatal("failed to set up rate limiting", err)
+		}
+		rateLimiter = middleware.NewRateLimiter(store, cfg.RateLimit.Groups)
+	}
+
+	idempotencyService := service.NewIdempotencyService(repository.NewIdempotencyRepository(db), cfg.Idempotency.TTL)
+	go idempotencyService.RunCleanup(workerCtx, cfg.Idempotency.CleanupInterval)
+	idempotency := middleware.Idempotency(idempotencyService)
+
+	migrationVersion, err := repository.LatestMigrationVersion("migrations")
+	if err != nil {
+		fatal("failed to read migrations", err)
+	}
+	healthService := service.NewHealthService(repository.NewHealthRepository(db), migrationVersion, cfg.Server.ReadinessTimeout)
+	healthHandler := handler.NewHealthHandler(healthService)
+
+	spec, err := docs.NewSpec()
+	if err != nil {
+		fatal("failed to build the OpenAPI document", err)
+	}
+	docsHandler := handler.NewDocsHandler(spec)
+
+	router := server.NewRouter(subscriptionHandler, webhookHandler, streamHandler, budgetHandler, userHandler, apiKeyHandler, healthHandler, docsHandler, authenticate, authorizer, resolveTenant, rateLimiter, middleware.ValidateRequest(spec), idempotency, logger)
 
 	srv := &http.Server{
 		Addr:         ":" + cfg.Server.Port,
@@ -47,31 +174,57 @@ func main() {
 	}
 
 	go func() {
-		log.Printf("Server starting on port %s", cfg.Server.Port)
+		slog.Info("server starting", "port", cfg.Server.Port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			fatal("failed to start server", err)
 		}
 	}()
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
+	slog.Info("shutting down server", "drain_delay", cfg.Server.DrainDelay.String())
+	healthService.Drain()
+	time.Sleep(cfg.Server.DrainDelay)
+	// Stopping the workers also stops the change feed, which ends open
+	// subscription streams so that Shutdown does not wait on them.
+	stopWorkers()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	// Not fatal: the deferred trace flush and closes must still run.
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		slog.Error("server forced to shutdown", "error", err)
 	}
 
-	log.Println("Server exited")
+	slog.Info("server exited")
 }
 
+// fatal logs err and exits, like log.Fatal.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// setupDatabase opens the database with every statement traced as a child
+// of the span in its context. Statements outside of a trace, such as the
+// workers' polling, are not traced.
 func setupDatabase(cfg *config.Config) (*sqlx.DB, error) {
-	db, err := sqlx.Connect("postgres", cfg.Database.GetDBConnString())
+	sqlDB, err := otelsql.Open("postgres", cfg.Database.GetDBConnString(),
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitConnectorConnect: true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
+	)
 	if err != nil {
 		return nil, err
 	}
+	db := sqlx.NewDb(sqlDB, "postgres")
 
 	db.SetMaxOpenConns(25)
 	db.SetMaxIdleConns(5)
@@ -81,7 +234,7 @@ func setupDatabase(cfg *config.Config) (*sqlx.DB, error) {
 		return nil, err
 	}
 
-	log.Println("Database connected successfully")
+	slog.Info("database connected")
 	return db, nil
 }
 
@@ -96,30 +249,26 @@ func runMigrations(cfg *config.Config) error {
 		return err
 	}
 
-	log.Println("Migrations applied successfully")
+	slog.Info("migrations applied")
 	return nil
 }
 
-func setupRouter(subscriptionHandler *handler.SubscriptionHandler) *gin.Engine {
-	gin.SetMode(gin.ReleaseMode)
-	
-	r := gin.Default()
-
-	r.Use(gin.Recovery())
-	r.Use(gin.Logger())
-
-	api := r.Group("/api/v1")
-	{
-		subscriptions := api.Group("/subscriptions")
-		{
-			subscriptions.POST("/", subscriptionHandler.CreateSubscription)
-			subscriptions.GET("/", subscriptionHandler.ListSubscriptions)
-			subscriptions.GET("/total", subscriptionHandler.GetTotalPrice)
-			subscriptions.GET("/:id", subscriptionHandler.GetSubscription)
-			subscriptions.PUT("/:id", subscriptionHandler.UpdateSubscription)
-			subscriptions.DELETE("/:id", subscriptionHandler.DeleteSubscription)
+func newOutboxPublisher(cfg config.OutboxConfig) (service.OutboxPublisher, func(), error) {
+	switch cfg.Publisher {
+	case "stdout":
+		return service.NewWriterPublisher(os.Stdout), func() {}, nil
+	case "file":
+		file, err := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, nil, err
 		}
+		return service.NewWriterPublisher(file), func() { file.Close() }, nil
+	case "http":
+		if cfg.HTTPURL == "" {
+			return nil, nil, fmt.Errorf("OUTBOX_HTTP_URL is required for the http publisher")
+		}
+		return service.NewHTTPPublisher(cfg.HTTPURL, cfg.RequestTimeout), func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown outbox publisher %q", cfg.Publisher)
 	}
-
-	return r
 }
@@ -0,0 +1,4 @@
+-- Row-level security does not apply to superusers, so the service connects
+-- as an ordinary role owning the database and the tables it migrates.
+CREATE ROLE subscription_app LOGIN PASSWORD 'subscription_app';
+ALTER DATABASE subscription_db OWNER TO subscription_app;
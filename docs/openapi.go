@@ -0,0 +1,367 @@
+// Package docs describes the HTTP API as an OpenAPI 3 document, built from
+// the route table below and the request and response types in model.
+package docs
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/problem"
+)
+
+const apiPrefix = "/api/v1"
+
+// monthPattern is the MM-YYYY format of every month and date the API
+// accepts.
+const monthPattern = `^(0[1-9]|1[0-2])-[0-9]{4}$`
+
+// monthFields are the request fields holding a month.
+var monthFields = map[string]bool{
+	"start_date":      true,
+	"end_date":        true,
+	"trial_end":       true,
+	"effective_month": true,
+	"month":           true,
+}
+
+// operation is one route of the router. Routes under /api/v1 also answer
+// 401, 403, 429 and 500, which are added to every one of them.
+type operation struct {
+	method   string
+	path     string
+	summary  string
+	query    []*openapi3.Parameter
+	header   []*openapi3.Parameter
+	body     string
+	optional bool
+	status   int
+	response *openapi3.SchemaRef
+	// content is the media type of the response when it is not JSON.
+	content string
+	errors  []int
+}
+
+func subscriptionFilter(params ...*openapi3.Parameter) []*openapi3.Parameter {
+	return append([]*openapi3.Parameter{
+		uuidQuery("user_id"),
+		openapi3.NewQueryParameter("service_name").WithSchema(openapi3.NewStringSchema()),
+		monthQuery("month"),
+		monthQuery("start_month"),
+		monthQuery("end_month"),
+	}, params...)
+}
+
+func operations() []operation {
+	return []operation{
+		{method: http.MethodGet, path: "/metrics", summary: "Prometheus metrics", status: http.StatusOK,
+			response: openapi3.NewStringSchema().NewRef(), content: "text/plain"},
+		{method: http.MethodGet, path: "/healthz", summary: "Liveness probe", status: http.StatusOK,
+			response: openapi3.NewObjectSchema().WithProperty("status", openapi3.NewStringSchema()).NewRef()},
+		{method: http.MethodGet, path: "/readyz", summary: "Readiness probe", status: http.StatusOK,
+			response: ref("Readiness"), errors: []int{http.StatusServiceUnavailable}},
+		{method: http.MethodGet, path: "/openapi.json", summary: "This document", status: http.StatusOK,
+			response: openapi3.NewObjectSchema().NewRef()},
+
+		{method: http.MethodPost, path: "/subscriptions/", summary: "Create a subscription",
+			header: []*openapi3.Parameter{openapi3.NewHeaderParameter("Idempotency-Key").
+				WithDescription("Repeating a request with the same key returns the first response instead of creating another subscription.").
+				WithSchema(openapi3.NewStringSchema().WithMinLength(1).WithMaxLength(255))},
+			body: "CreateSubscriptionRequest", status: http.StatusCreated, response: ref("Subscription"),
+			errors: []int{http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity}},
+		{method: http.MethodGet, path: "/subscriptions/", summary: "List subscriptions",
+			query: subscriptionFilter(
+				openapi3.NewQueryParameter("status").WithSchema(openapi3.NewStringSchema().WithEnum(
+					model.SubscriptionStatusTrial, model.SubscriptionStatusActive, model.SubscriptionStatusPaused,
+					model.SubscriptionStatusCancelled, model.SubscriptionStatusExpired)),
+				intQuery("limit", 10), intQuery("offset", 0)),
+			status: http.StatusOK, response: page("Subscription"), errors: []int{http.StatusBadRequest}},
+		{method: http.MethodGet, path: "/subscriptions/total", summary: "Total price of subscriptions over a period",
+			query: subscriptionFilter(), status: http.StatusOK,
+			response: openapi3.NewObjectSchema().WithProperty("total_price", openapi3.NewIntegerSchema()).NewRef(),
+			errors:   []int{http.StatusBadRequest}},
+		{method: http.MethodGet, path: "/subscriptions/stream", summary: "Stream subscription changes as Server-Sent Events",
+			query: []*openapi3.Parameter{uuidQuery("user_id")}, status: http.StatusOK,
+			response: ref("SubscriptionChange"), content: "text/event-stream", errors: []int{http.StatusBadRequest}},
+		{method: http.MethodGet, path: "/subscriptions/overlaps", summary: "List overlapping subscriptions",
+			query: []*openapi3.Parameter{uuidQuery("user_id")}, status: http.StatusOK,
+			response: list("SubscriptionOverlap"), errors: []int{http.StatusBadRequest}},
+		{method: http.MethodGet, path: "/subscriptions/:id", summary: "Get a subscription",
+			status: http.StatusOK, response: ref("Subscription"),
+			errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{method: http.MethodPut, path: "/subscriptions/:id", summary: "Update a subscription",
+			body: "UpdateSubscriptionRequest", status: http.StatusOK,
+			response: openapi3.NewObjectSchema().
+				WithProperty("message", openapi3.NewStringSchema()).
+				WithPropertyRef("budget_warning", ref("BudgetWarning")).NewRef(),
+			errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity}},
+		{method: http.MethodDelete, path: "/subscriptions/:id", summary: "Delete a subscription",
+			status: http.StatusOK, response: ref("Message"), errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{method: http.MethodPost, path: "/subscriptions/:id/restore", summary: "Restore a deleted subscription",
+			status: http.StatusOK, response: ref("Subscription"),
+			errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+		{method: http.MethodPost, path: "/subscriptions/:id/pause", summary: "Pause a subscription",
+			body: "PauseSubscriptionRequest", optional: true, status: http.StatusOK, response: ref("Subscription"),
+			errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+		{method: http.MethodPost, path: "/subscriptions/:id/resume", summary: "Resume a paused subscription",
+			body: "ResumeSubscriptionRequest", optional: true, status: http.StatusOK, response: ref("Subscription"),
+			errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+		{method: http.MethodPost, path: "/subscriptions/:id/status", summary: "Change the status of a subscription",
+			body: "ChangeStatusRequest", status: http.StatusOK, response: ref("Subscription"),
+			errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+		{method: http.MethodPost, path: "/subscriptions/:id/cancel", summary: "Cancel a subscription",
+			body: "CancelSubscriptionRequest", optional: true, status: http.StatusOK, response: ref("Subscription"),
+			errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+		{method: http.MethodGet, path: "/subscriptions/:id/members", summary: "List the members sharing a subscription",
+			status: http.StatusOK, response: list("SubscriptionMember"),
+			errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{method: http.MethodPut, path: "/subscriptions/:id/members/:user_id", summary: "Add or update a member",
+			body: "SetMemberRequest", status: http.StatusOK, response: ref("SubscriptionMember"),
+			errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{method: http.MethodDelete, path: "/subscriptions/:id/members/:user_id", summary: "Remove a member",
+			status: http.StatusOK, response: ref("Message"), errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+		{method: http.MethodPost, path: "/users/", summary: "Create a user",
+			body: "CreateUserRequest", status: http.StatusCreated, response: ref("User"),
+			errors: []int{http.StatusBadRequest, http.StatusConflict}},
+		{method: http.MethodGet, path: "/users/", summary: "List users",
+			query:  []*openapi3.Parameter{intQuery("limit", 10), intQuery("offset", 0)},
+			status: http.StatusOK, response: page("User")},
+		{method: http.MethodGet, path: "/users/:user_id", summary: "Get a user",
+			status: http.StatusOK, response: ref("User"), errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{method: http.MethodPut, path: "/users/:user_id", summary: "Update a user",
+			body: "UpdateUserRequest", status: http.StatusOK, response: ref("User"),
+			errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+		{method: http.MethodDelete, path: "/users/:user_id", summary: "Delete a user",
+			status: http.StatusOK, response: ref("Message"),
+			errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+		{method: http.MethodGet, path: "/users/:user_id/summary", summary: "Spending summary of a user",
+			status: http.StatusOK, response: ref("UserSummary"), errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{method: http.MethodGet, path: "/users/:user_id/export", summary: "Export everything stored about a user",
+			status: http.StatusOK, response: ref("UserExport"), errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{method: http.MethodDelete, path: "/users/:user_id/data", summary: "Erase a user's data",
+			query: []*openapi3.Parameter{
+				openapi3.NewQueryParameter("mode").WithSchema(
+					openapi3.NewStringSchema().WithEnum(model.ErasureModeDelete, model.ErasureModeAnonymise)),
+				openapi3.NewQueryParameter("tenants").WithSchema(openapi3.NewStringSchema().WithEnum("all")),
+			},
+			status: http.StatusOK, response: ref("ErasureReceipt"),
+			errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound}},
+		{method: http.MethodGet, path: "/users/:user_id/renewals", summary: "Upcoming renewals of a user",
+			query: []*openapi3.Parameter{intQuery("within_days", 30)}, status: http.StatusOK,
+			response: openapi3.NewObjectSchema().
+				WithPropertyRef("data", arrayOf("Renewal")).
+				WithProperty("total_amount", openapi3.NewIntegerSchema()).
+				WithProperty("within_days", openapi3.NewIntegerSchema()).NewRef(),
+			errors: []int{http.StatusBadRequest}},
+		{method: http.MethodPut, path: "/users/:user_id/budget", summary: "Set a user's monthly budget",
+			body: "SetBudgetRequest", status: http.StatusOK, response: ref("Budget"), errors: []int{http.StatusBadRequest}},
+		{method: http.MethodGet, path: "/users/:user_id/budget", summary: "Get a user's monthly budget",
+			status: http.StatusOK, response: ref("Budget"), errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{method: http.MethodGet, path: "/users/:user_id/budget/status", summary: "Spend against a user's budget",
+			query: []*openapi3.Parameter{monthQuery("month")}, status: http.StatusOK, response: ref("BudgetStatus"),
+			errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+		{method: http.MethodPost, path: "/webhooks/", summary: "Register a webhook",
+			body: "CreateWebhookRequest", status: http.StatusCreated, response: ref("Webhook"),
+			errors: []int{http.StatusBadRequest}},
+		{method: http.MethodGet, path: "/webhooks/", summary: "List webhooks",
+			status: http.StatusOK, response: list("Webhook")},
+		{method: http.MethodGet, path: "/webhooks/:id", summary: "Get a webhook",
+			status: http.StatusOK, response: ref("Webhook"), errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{method: http.MethodDelete, path: "/webhooks/:id", summary: "Delete a webhook",
+			status: http.StatusOK, response: ref("Message"), errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{method: http.MethodGet, path: "/webhooks/:id/deliveries", summary: "List deliveries of a webhook",
+			query: []*openapi3.Parameter{intQuery("limit", 20)}, status: http.StatusOK,
+			response: list("WebhookDelivery"), errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+		{method: http.MethodPost, path: "/api-keys/", summary: "Issue an API key",
+			body: "CreateAPIKeyRequest", status: http.StatusCreated, response: ref("IssuedAPIKey"),
+			errors: []int{http.StatusBadRequest}},
+		{method: http.MethodGet, path: "/api-keys/", summary: "List API keys",
+			status: http.StatusOK, response: list("APIKey")},
+		{method: http.MethodDelete, path: "/api-keys/:id", summary: "Revoke an API key",
+			status: http.StatusOK, response: ref("Message"), errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	}
+}
+
+// NewSpec builds the OpenAPI document of every route the router serves.
+func NewSpec() (*openapi3.T, error) {
+	schemas, err := modelSchemas()
+	if err != nil {
+		return nil, err
+	}
+	for name, schema := range schemas {
+		if !strings.HasSuffix(name, "Request") {
+			continue
+		}
+		for field, property := range schema.Value.Properties {
+			if monthFields[field] {
+				property.Value.Pattern = monthPattern
+			}
+		}
+	}
+
+	spec := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:       "Subscription Service API",
+			Description: "Aggregates users' online subscriptions. Months are given as MM-YYYY.",
+			Version:     "1.0.0",
+		},
+		Paths: openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas: schemas,
+			SecuritySchemes: openapi3.SecuritySchemes{
+				"bearerAuth": &openapi3.SecuritySchemeRef{Value: openapi3.NewJWTSecurityScheme()},
+				"apiKeyAuth": &openapi3.SecuritySchemeRef{Value: openapi3.NewSecurityScheme().
+					WithType("apiKey").WithIn("header").WithName("Authorization").
+					WithDescription(`An API key given as "ApiKey <key>".`)},
+			},
+		},
+		Security: openapi3.SecurityRequirements{
+			openapi3.NewSecurityRequirement().Authenticate("bearerAuth"),
+			openapi3.NewSecurityRequirement().Authenticate("apiKeyAuth"),
+		},
+	}
+
+	for _, op := range operations() {
+		path := op.path
+		if op.isAPI() {
+			path = apiPrefix + path
+		}
+		path = PathFromRoute(path)
+
+		item := spec.Paths.Value(path)
+		if item == nil {
+			item = &openapi3.PathItem{}
+			spec.Paths.Set(path, item)
+		}
+		item.SetOperation(op.method, op.build(path))
+	}
+
+	// Resolving the references lets the document be validated and used to
+	// validate requests.
+	if err := openapi3.NewLoader().ResolveRefsIn(spec, nil); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// PathFromRoute turns a gin route such as /users/:user_id into the
+// OpenAPI path /users/{user_id}.
+func PathFromRoute(route string) string {
+	return routeParam.ReplaceAllString(route, "{$1}")
+}
+
+var routeParam = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+func (op operation) isAPI() bool {
+	switch op.path {
+	case "/metrics", "/healthz", "/readyz", "/openapi.json":
+		return false
+	}
+	return true
+}
+
+func (op operation) build(path string) *openapi3.Operation {
+	operation := openapi3.NewOperation()
+	operation.Summary = op.summary
+	operation.Tags = []string{tag(path)}
+	operation.Responses = openapi3.NewResponsesWithCapacity(len(op.errors) + 5)
+
+	for _, name := range routeParam.FindAllStringSubmatch(op.path, -1) {
+		operation.AddParameter(openapi3.NewPathParameter(name[1]).WithSchema(openapi3.NewUUIDSchema()))
+	}
+	for _, param := range op.query {
+		operation.AddParameter(param)
+	}
+	for _, param := range op.header {
+		operation.AddParameter(param)
+	}
+
+	if op.body != "" {
+		operation.RequestBody = &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().
+			WithRequired(!op.optional).
+			WithJSONSchemaRef(ref(op.body))}
+	}
+
+	content := op.content
+	if content == "" {
+		content = "application/json"
+	}
+	operation.AddResponse(op.status, openapi3.NewResponse().
+		WithDescription(http.StatusText(op.status)).
+		WithContent(openapi3.NewContentWithSchemaRef(op.response, []string{content})))
+
+	errors := op.errors
+	if op.isAPI() {
+		errors = append(errors, http.StatusUnauthorized, http.StatusForbidden,
+			http.StatusTooManyRequests, http.StatusInternalServerError)
+	} else {
+		operation.Security = &openapi3.SecurityRequirements{}
+	}
+	for _, status := range errors {
+		response := openapi3.NewResponse().
+			WithDescription(http.StatusText(status)).
+			WithJSONSchemaRef(ref("Error"))
+		// Validation against this document and the subscription handlers
+		// answer with a problem, the rest with an error body.
+		if op.isAPI() {
+			response.Content[problem.ContentType] = openapi3.NewMediaType().WithSchemaRef(ref("Problem"))
+		}
+		operation.AddResponse(status, response)
+	}
+	// /readyz reports its checks in the body of a 503 too.
+	if op.path == "/readyz" {
+		operation.Responses.Status(http.StatusServiceUnavailable).Value.Content =
+			openapi3.NewContentWithJSONSchemaRef(ref("Readiness"))
+	}
+	return operation
+}
+
+func tag(path string) string {
+	group, ok := strings.CutPrefix(path, apiPrefix+"/")
+	if !ok {
+		return "operations"
+	}
+	group, _, _ = strings.Cut(group, "/")
+	return group
+}
+
+func ref(name string) *openapi3.SchemaRef {
+	return openapi3.NewSchemaRef("#/components/schemas/"+name, nil)
+}
+
+func arrayOf(name string) *openapi3.SchemaRef {
+	return openapi3.NewSchemaRef("", &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeArray}, Items: ref(name)})
+}
+
+// list is the {"data": [...]} body of an unpaginated listing.
+func list(name string) *openapi3.SchemaRef {
+	return openapi3.NewObjectSchema().WithPropertyRef("data", arrayOf(name)).NewRef()
+}
+
+// page is the body of a paginated listing.
+func page(name string) *openapi3.SchemaRef {
+	return openapi3.NewObjectSchema().
+		WithPropertyRef("data", arrayOf(name)).
+		WithProperty("total", openapi3.NewIntegerSchema()).
+		WithProperty("limit", openapi3.NewIntegerSchema()).
+		WithProperty("offset", openapi3.NewIntegerSchema()).NewRef()
+}
+
+func uuidQuery(name string) *openapi3.Parameter {
+	return openapi3.NewQueryParameter(name).WithSchema(openapi3.NewUUIDSchema())
+}
+
+func monthQuery(name string) *openapi3.Parameter {
+	return openapi3.NewQueryParameter(name).WithSchema(openapi3.NewStringSchema().WithPattern(monthPattern))
+}
+
+func intQuery(name string, defaultValue int) *openapi3.Parameter {
+	return openapi3.NewQueryParameter(name).WithSchema(openapi3.NewIntegerSchema().WithDefault(defaultValue))
+}
@@ -0,0 +1,148 @@
+package docs
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/problem"
+)
+
+// models are the request and response bodies documented as component
+// schemas, named after their types.
+var models = []any{
+	model.Subscription{},
+	model.CreateSubscriptionRequest{},
+	model.UpdateSubscriptionRequest{},
+	model.ChangeStatusRequest{},
+	model.CancelSubscriptionRequest{},
+	model.PauseSubscriptionRequest{},
+	model.ResumeSubscriptionRequest{},
+	model.SubscriptionMember{},
+	model.SetMemberRequest{},
+	model.SubscriptionChange{},
+	model.SubscriptionOverlap{},
+	model.Renewal{},
+	model.BudgetWarning{},
+	model.User{},
+	model.CreateUserRequest{},
+	model.UpdateUserRequest{},
+	model.UserSummary{},
+	model.UserExport{},
+	model.ErasureReceipt{},
+	model.Budget{},
+	model.SetBudgetRequest{},
+	model.BudgetStatus{},
+	model.Webhook{},
+	model.CreateWebhookRequest{},
+	model.WebhookDelivery{},
+	model.APIKey{},
+	model.CreateAPIKeyRequest{},
+	model.IssuedAPIKey{},
+	model.Readiness{},
+	problem.Problem{},
+}
+
+func modelSchemas() (openapi3.Schemas, error) {
+	schemas := openapi3.Schemas{
+		"Error": openapi3.NewObjectSchema().
+			WithProperty("error", openapi3.NewStringSchema()).
+			WithProperty("conflicting_id", openapi3.NewUUIDSchema()).
+			WithRequired([]string{"error"}).NewRef(),
+		"Message": openapi3.NewObjectSchema().
+			WithProperty("message", openapi3.NewStringSchema()).
+			WithRequired([]string{"message"}).NewRef(),
+	}
+
+	for _, value := range models {
+		ref, err := openapi3gen.NewSchemaRefForValue(value, nil, openapi3gen.SchemaCustomizer(applyBindingTags))
+		if err != nil {
+			return nil, err
+		}
+		schemas[reflect.TypeOf(value).Name()] = openapi3.NewSchemaRef("", ref.Value)
+	}
+	return schemas, nil
+}
+
+// applyBindingTags carries the gin validation rules of a field over to its
+// schema, so that the document states what requests are accepted. Rules
+// after "dive" apply to the items of a slice.
+func applyBindingTags(_ string, t reflect.Type, tag reflect.StructTag, schema *openapi3.Schema) error {
+	if t.Kind() == reflect.Struct && schema.Properties != nil {
+		schema.Required = requiredFields(t)
+		return nil
+	}
+
+	binding, ok := tag.Lookup("binding")
+	if !ok {
+		return nil
+	}
+	own, items, dived := strings.Cut(binding, ",dive")
+	if dived && t.Kind() != reflect.Slice {
+		own = strings.TrimPrefix(items, ",")
+	}
+
+	for _, rule := range strings.Split(own, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "uuid":
+			schema.Format = "uuid"
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, value)
+			}
+		case "min", "max":
+			n, err := strconv.ParseUint(param, 10, 64)
+			if err != nil {
+				return err
+			}
+			setBound(schema, name == "min", n)
+		}
+	}
+	return nil
+}
+
+func setBound(schema *openapi3.Schema, lower bool, n uint64) {
+	switch {
+	case schema.Type.Is(openapi3.TypeString) && lower:
+		schema.MinLength = n
+	case schema.Type.Is(openapi3.TypeString):
+		schema.MaxLength = &n
+	case schema.Type.Is(openapi3.TypeArray) && lower:
+		schema.MinItems = n
+	case schema.Type.Is(openapi3.TypeArray):
+		schema.MaxItems = &n
+	case lower:
+		schema.Min = openapi3.Float64Ptr(float64(n))
+	default:
+		schema.Max = openapi3.Float64Ptr(float64(n))
+	}
+}
+
+// requiredFields lists the JSON names of the fields of t, embedded structs
+// included, that are bound with "required".
+func requiredFields(t reflect.Type) []string {
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			required = append(required, requiredFields(field.Type)...)
+			continue
+		}
+		rules := strings.Split(field.Tag.Get("binding"), ",")
+		if rules[0] != "required" {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		required = append(required, name)
+	}
+	return required
+}
@@ -0,0 +1,184 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+)
+
+const (
+	ActionRead    = "subscriptions:read"
+	ActionWrite   = "subscriptions:write"
+	ActionDelete  = "subscriptions:delete"
+	ActionRestore = "subscriptions:restore"
+
+	// User actions are on a user's record and the data kept under it;
+	// webhook actions are on the tenant's webhooks, which have no owner.
+	ActionUsersRead     = "users:read"
+	ActionUsersWrite    = "users:write"
+	ActionWebhooksRead  = "webhooks:read"
+	ActionWebhooksWrite = "webhooks:write"
+
+	// anyAction in a policy grants every action.
+	anyAction = "*"
+)
+
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+	// RoleService is the default role of API keys.
+	RoleService = "service"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+// Authorizer decides whether the caller found in ctx may perform action on
+// the data of ownerID. An empty ownerID stands for every user's data.
+type Authorizer interface {
+	Authorize(ctx context.Context, action, ownerID string) error
+}
+
+// RolePermissions lists the actions a role may perform on its own data and
+// on anyone's.
+type RolePermissions struct {
+	Own []string `json:"own"`
+	Any []string `json:"any"`
+}
+
+// Policy maps roles to permissions. Callers without a role known to the
+// policy get DefaultRole.
+type Policy struct {
+	DefaultRole string                     `json:"default_role"`
+	Roles       map[string]RolePermissions `json:"roles"`
+}
+
+// DefaultPolicy lets users manage their own data, support staff read any
+// subscription and restore deleted ones, services read and write anyone's
+// subscriptions and users and manage webhooks, and admins do everything.
+func DefaultPolicy() *Policy {
+	own := []string{ActionRead, ActionWrite, ActionDelete, ActionUsersRead, ActionUsersWrite}
+	return &Policy{
+		DefaultRole: RoleUser,
+		Roles: map[string]RolePermissions{
+			RoleUser:    {Own: own},
+			RoleSupport: {Own: own, Any: []string{ActionRead, ActionRestore}},
+			RoleService: {Any: []string{
+				ActionRead, ActionWrite, ActionUsersRead, ActionUsersWrite, ActionWebhooksRead, ActionWebhooksWrite,
+			}},
+			RoleAdmin: {Any: []string{anyAction}},
+		},
+	}
+}
+
+// LoadPolicy reads a JSON policy file, or returns the default policy when
+// path is empty.
+func LoadPolicy(path string) (*Policy, error) {
+	if path == "" {
+		return DefaultPolicy(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("parse policy: %w", err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (p *Policy) validate() error {
+	if _, ok := p.Roles[p.DefaultRole]; !ok {
+		return fmt.Errorf("default role %q is not defined", p.DefaultRole)
+	}
+	for role, permissions := range p.Roles {
+		for _, action := range append(append([]string{}, permissions.Own...), permissions.Any...) {
+			switch action {
+			case ActionRead, ActionWrite, ActionDelete, ActionRestore,
+				ActionUsersRead, ActionUsersWrite, ActionWebhooksRead, ActionWebhooksWrite, anyAction:
+			default:
+				return fmt.Errorf("role %q: unknown action %q", role, action)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Policy) Authorize(ctx context.Context, action, ownerID string) error {
+	principal := PrincipalFrom(ctx)
+	if principal == nil {
+		return ErrForbidden
+	}
+	if principal == systemPrincipal {
+		return nil
+	}
+
+	for _, role := range p.rolesOf(principal) {
+		permissions := p.Roles[role]
+		if grants(permissions.Any, action) {
+			return nil
+		}
+		if ownerID != "" && ownerID == principal.Subject && grants(permissions.Own, action) {
+			return nil
+		}
+	}
+	return ErrForbidden
+}
+
+// rolesOf returns the caller's roles known to the policy. Admins, as marked
+// by authentication, always have the admin role.
+func (p *Policy) rolesOf(principal *model.Principal) []string {
+	roles := make([]string, 0, len(principal.Roles)+1)
+	if principal.Admin {
+		roles = append(roles, RoleAdmin)
+	}
+	for _, role := range principal.Roles {
+		if _, ok := p.Roles[role]; ok {
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) == 0 {
+		roles = append(roles, p.DefaultRole)
+	}
+	return roles
+}
+
+func grants(actions []string, action string) bool {
+	for _, granted := range actions {
+		if granted == action || granted == anyAction {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// systemPrincipal stands for the service's own background work.
+var systemPrincipal = &model.Principal{Subject: "system"}
+
+func WithPrincipal(ctx context.Context, principal *model.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the caller stored in ctx, or nil.
+func PrincipalFrom(ctx context.Context) *model.Principal {
+	principal, _ := ctx.Value(principalKey{}).(*model.Principal)
+	return principal
+}
+
+// SystemContext marks ctx as the service acting on its own behalf, which
+// every policy allows.
+func SystemContext(ctx context.Context) context.Context {
+	return WithPrincipal(ctx, systemPrincipal)
+}
@@ -2,7 +2,7 @@ package config
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"strconv"
 	"time"
@@ -11,14 +11,27 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	Webhook     WebhookConfig
+	Outbox      OutboxConfig
+	Auth        AuthConfig
+	Tenant      TenantConfig
+	RateLimit   RateLimitConfig
+	Idempotency IdempotencyConfig
+	Erasure     ErasureConfig
+	Log         LogConfig
+	Tracing     TracingConfig
 }
 
 type ServerConfig struct {
-	Port         string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
+	Port             string
+	ReadTimeout      time.Duration
+	WriteTimeout     time.Duration
+	ReadinessTimeout time.Duration
+	// DrainDelay is how long the server keeps serving after readiness
+	// turns off at shutdown, for load balancers to notice.
+	DrainDelay time.Duration
 }
 
 type DatabaseConfig struct {
@@ -30,17 +43,111 @@ type DatabaseConfig struct {
 	SSLMode  string
 }
 
+type WebhookConfig struct {
+	PollInterval        time.Duration
+	RequestTimeout      time.Duration
+	RetryBaseDelay      time.Duration
+	RetryMaxDelay       time.Duration
+	MaxAttempts         int
+	BatchSize           int
+	RenewalDaysAhead    int
+	RenewalScanInterval time.Duration
+	// AllowPrivateTargets lets webhooks reach private, loopback and
+	// link-local addresses and plain http, for local development.
+	AllowPrivateTargets bool
+}
+
+type OutboxConfig struct {
+	Publisher      string
+	FilePath       string
+	HTTPURL        string
+	RequestTimeout time.Duration
+	PollInterval   time.Duration
+	BatchSize      int
+}
+
+// AuthConfig configures JWT bearer authentication. Tokens are verified
+// with the HS256 secret, the RS256 public key and the keys of the JWKS
+// file, whichever are set. PolicyFile holds the role-based access policy;
+// without it the built-in policy applies. APIKeyRole is the policy role of
+// API keys.
+type AuthConfig struct {
+	Enabled            bool
+	HS256Secret        string
+	RS256PublicKeyFile string
+	JWKSFile           string
+	Issuer             string
+	Audience           string
+	RolesClaim         string
+	AdminRole          string
+	TenantClaim        string
+	Leeway             time.Duration
+	PolicyFile         string
+	APIKeyRole         string
+}
+
+// TenantConfig names the request header choosing the tenant and the tenant
+// of requests that choose none.
+type TenantConfig struct {
+	Header  string
+	Default string
+}
+
+// RateLimitConfig sets the token-bucket limits of each route group. Store
+// is "memory" for limits per replica or "postgres" for limits shared by
+// all replicas.
+type RateLimitConfig struct {
+	Enabled bool
+	Store   string
+	Groups  map[string]RateLimit
+}
+
+// RateLimit lets a client make PerMinute requests a minute on average, and
+// up to Burst at once.
+type RateLimit struct {
+	PerMinute int
+	Burst     int
+}
+
+// IdempotencyConfig sets how long responses to requests with an
+// Idempotency-Key are kept for replay, and how often expired ones are
+// deleted.
+type IdempotencyConfig struct {
+	TTL             time.Duration
+	CleanupInterval time.Duration
+}
+
+// ErasureConfig holds the server-side key erasure receipts identify users
+// by, as an HMAC of their id.
+type ErasureConfig struct {
+	HashKey string
+}
+
+// LogConfig sets the lowest level logged: debug, info, warn or error.
+type LogConfig struct {
+	Level string
+}
+
+// TracingConfig chooses where spans go, "none", "stdout" or "otlp", and
+// the share of traces started here that are sampled.
+type TracingConfig struct {
+	Exporter    string
+	SampleRatio float64
+}
+
 func LoadConfig() *Config {
 	err := godotenv.Load()
 	if err != nil {
-		log.Println("Warning: .env file not found, using environment variables")
+		slog.Warn(".env file not found, using environment variables")
 	}
 
 	return &Config{
 		Server: ServerConfig{
-			Port:         getEnv("SERVER_PORT", "8080"),
-			ReadTimeout:  time.Duration(getEnvAsInt("SERVER_READ_TIMEOUT", 10)) * time.Second,
-			WriteTimeout: time.Duration(getEnvAsInt("SERVER_WRITE_TIMEOUT", 10)) * time.Second,
+			Port:             getEnv("SERVER_PORT", "8080"),
+			ReadTimeout:      time.Duration(getEnvAsInt("SERVER_READ_TIMEOUT", 10)) * time.Second,
+			WriteTimeout:     time.Duration(getEnvAsInt("SERVER_WRITE_TIMEOUT", 10)) * time.Second,
+			ReadinessTimeout: time.Duration(getEnvAsInt("SERVER_READINESS_TIMEOUT", 2)) * time.Second,
+			DrainDelay:       time.Duration(getEnvAsInt("SERVER_DRAIN_DELAY", 5)) * time.Second,
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -50,6 +157,68 @@ func LoadConfig() *Config {
 			Name:     getEnv("DB_NAME", "subscription_db"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
+		Webhook: WebhookConfig{
+			PollInterval:        time.Duration(getEnvAsInt("WEBHOOK_POLL_INTERVAL", 5)) * time.Second,
+			RequestTimeout:      time.Duration(getEnvAsInt("WEBHOOK_REQUEST_TIMEOUT", 10)) * time.Second,
+			RetryBaseDelay:      time.Duration(getEnvAsInt("WEBHOOK_RETRY_BASE_DELAY", 30)) * time.Second,
+			RetryMaxDelay:       time.Duration(getEnvAsInt("WEBHOOK_RETRY_MAX_DELAY", 3600)) * time.Second,
+			MaxAttempts:         getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 8),
+			BatchSize:           getEnvAsInt("WEBHOOK_BATCH_SIZE", 50),
+			RenewalDaysAhead:    getEnvAsInt("WEBHOOK_RENEWAL_DAYS_AHEAD", 3),
+			RenewalScanInterval: time.Duration(getEnvAsInt("WEBHOOK_RENEWAL_SCAN_INTERVAL", 3600)) * time.Second,
+			AllowPrivateTargets: getEnvAsBool("WEBHOOK_ALLOW_PRIVATE_TARGETS", false),
+		},
+		Outbox: OutboxConfig{
+			Publisher:      getEnv("OUTBOX_PUBLISHER", "stdout"),
+			FilePath:       getEnv("OUTBOX_FILE_PATH", "outbox.jsonl"),
+			HTTPURL:        getEnv("OUTBOX_HTTP_URL", ""),
+			RequestTimeout: time.Duration(getEnvAsInt("OUTBOX_REQUEST_TIMEOUT", 10)) * time.Second,
+			PollInterval:   time.Duration(getEnvAsInt("OUTBOX_POLL_INTERVAL", 2)) * time.Second,
+			BatchSize:      getEnvAsInt("OUTBOX_BATCH_SIZE", 100),
+		},
+		Auth: AuthConfig{
+			Enabled:            getEnvAsBool("AUTH_ENABLED", true),
+			HS256Secret:        getEnv("JWT_HS256_SECRET", ""),
+			RS256PublicKeyFile: getEnv("JWT_RS256_PUBLIC_KEY_FILE", ""),
+			JWKSFile:           getEnv("JWT_JWKS_FILE", ""),
+			Issuer:             getEnv("JWT_ISSUER", ""),
+			Audience:           getEnv("JWT_AUDIENCE", ""),
+			RolesClaim:         getEnv("JWT_ROLES_CLAIM", "roles"),
+			AdminRole:          getEnv("JWT_ADMIN_ROLE", "admin"),
+			TenantClaim:        getEnv("JWT_TENANT_CLAIM", "tenant_id"),
+			Leeway:             time.Duration(getEnvAsInt("JWT_LEEWAY", 30)) * time.Second,
+			PolicyFile:         getEnv("RBAC_POLICY_FILE", ""),
+			APIKeyRole:         getEnv("API_KEY_ROLE", "service"),
+		},
+		Tenant: TenantConfig{
+			Header:  getEnv("TENANT_HEADER", "X-Tenant-ID"),
+			Default: getEnv("TENANT_DEFAULT", "default"),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: getEnvAsBool("RATE_LIMIT_ENABLED", true),
+			Store:   getEnv("RATE_LIMIT_STORE", "memory"),
+			Groups: map[string]RateLimit{
+				"subscriptions": getRateLimit("SUBSCRIPTIONS", 600, 100),
+				"users":         getRateLimit("USERS", 300, 50),
+				"webhooks":      getRateLimit("WEBHOOKS", 60, 20),
+				"api_keys":      getRateLimit("API_KEYS", 30, 10),
+				"auth":          getRateLimit("AUTH", 30, 10),
+			},
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:             time.Duration(getEnvAsInt("IDEMPOTENCY_TTL", 24)) * time.Hour,
+			CleanupInterval: time.Duration(getEnvAsInt("IDEMPOTENCY_CLEANUP_INTERVAL", 600)) * time.Second,
+		},
+		Erasure: ErasureConfig{
+			HashKey: getEnv("ERASURE_HASH_KEY", ""),
+		},
+		Log: LogConfig{
+			Level: getEnv("LOG_LEVEL", "info"),
+		},
+		Tracing: TracingConfig{
+			Exporter:    getEnv("TRACING_EXPORTER", "none"),
+			SampleRatio: getEnvAsFloat("TRACING_SAMPLE_RATIO", 1),
+		},
 	}
 }
 
@@ -74,3 +243,27 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getRateLimit reads RATE_LIMIT_<group>_PER_MINUTE and _BURST.
+func getRateLimit(group string, perMinute, burst int) RateLimit {
+	return RateLimit{
+		PerMinute: getEnvAsInt("RATE_LIMIT_"+group+"_PER_MINUTE", perMinute),
+		Burst:     getEnvAsInt("RATE_LIMIT_"+group+"_BURST", burst),
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/t5129001t-jpg/subscription-service/internal/problem"
+)
+
+// abortWithUnexpected answers an error the client cannot act on. The error
+// is kept on the request to be logged, and its message, which may come
+// from Postgres, is not shown.
+func abortWithUnexpected(c *gin.Context, err error) {
+	c.Error(err)
+	problem.Abort(c, problem.New(http.StatusInternalServerError, "An unexpected error occurred."))
+}
+
+func abortWithInvalidField(c *gin.Context, field, message string) {
+	problem.Abort(c, problem.Invalid(problem.FieldError{Field: field, Message: message}))
+}
+
+// abortWithBindingError answers a body that could not be bound to req,
+// naming each invalid field by its JSON name.
+func abortWithBindingError(c *gin.Context, err error, req any) {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]problem.FieldError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields = append(fields, problem.FieldError{
+				Field:   "body." + jsonName(req, fieldErr.StructField()),
+				Message: ruleMessage(fieldErr),
+			})
+		}
+		problem.Abort(c, problem.Invalid(fields...))
+	case errors.As(err, &typeErr):
+		abortWithInvalidField(c, "body."+typeErr.Field, "must be of type "+typeErr.Type.String())
+	default:
+		abortWithInvalidField(c, "body", err.Error())
+	}
+}
+
+func jsonName(req any, structField string) string {
+	field, ok := reflect.TypeOf(req).Elem().FieldByName(structField)
+	if !ok {
+		return structField
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+func ruleMessage(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return "is required"
+	case "min", "max":
+		bound := "at least "
+		if err.Tag() == "max" {
+			bound = "at most "
+		}
+		if err.Kind() == reflect.String {
+			return "must be " + bound + err.Param() + " characters long"
+		}
+		return "must be " + bound + err.Param()
+	case "oneof":
+		return "must be one of: " + err.Param()
+	case "uuid":
+		return "must be a UUID"
+	default:
+		return "failed the " + err.Tag() + " check"
+	}
+}
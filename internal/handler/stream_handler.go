@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/t5129001t-jpg/subscription-service/internal/authz"
+	"github.com/t5129001t-jpg/subscription-service/internal/middleware"
+	"github.com/t5129001t-jpg/subscription-service/internal/problem"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
+)
+
+type StreamHandler struct {
+	feed       *service.ChangeFeed
+	authorizer authz.Authorizer
+	heartbeat  time.Duration
+}
+
+// NewStreamHandler decides who may stream whose changes with authorizer,
+// which is nil when authentication is disabled.
+func NewStreamHandler(feed *service.ChangeFeed, authorizer authz.Authorizer, heartbeat time.Duration) *StreamHandler {
+	return &StreamHandler{feed: feed, authorizer: authorizer, heartbeat: heartbeat}
+}
+
+// StreamSubscriptions sends the tenant's subscription changes as
+// Server-Sent Events, optionally limited to one user. Comment lines are sent as a heartbeat so
+// that proxies keep idle streams open.
+func (h *StreamHandler) StreamSubscriptions(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID != "" {
+		if _, err := uuid.Parse(userID); err != nil {
+			abortWithInvalidField(c, "query.user_id", "invalid UUID format")
+			return
+		}
+	}
+	userID, ok := h.streamedUserID(c, userID)
+	if !ok {
+		problem.Abort(c, problem.New(http.StatusForbidden, authz.ErrForbidden.Error()))
+		return
+	}
+
+	tenantID, _ := tenant.FromContext(c.Request.Context())
+
+	// The stream outlives the server's write timeout.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	changes, unsubscribe := h.feed.Subscribe(tenantID, userID)
+	defer unsubscribe()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case change, ok := <-changes:
+			if !ok {
+				return false
+			}
+			change.MemberIDs = nil
+			c.Render(-1, sse.Event{
+				Id:    fmt.Sprintf("%s:%d", change.ID, change.OccurredAt.UnixNano()),
+				Event: change.Event,
+				Data:  change,
+			})
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
+
+// streamedUserID returns the user whose changes the caller may stream:
+// the requested one, or everyone when none is requested, if the policy
+// lets the caller read their subscriptions, and otherwise the caller
+// themselves when no other user was requested.
+func (h *StreamHandler) streamedUserID(c *gin.Context, requested string) (string, bool) {
+	principal := middleware.PrincipalFrom(c)
+	if h.authorizer == nil || principal == nil {
+		return requested, true
+	}
+
+	ctx := c.Request.Context()
+	if h.authorizer.Authorize(ctx, authz.ActionRead, requested) == nil {
+		return requested, true
+	}
+	if requested != "" && requested != principal.Subject {
+		return "", false
+	}
+	return principal.Subject, h.authorizer.Authorize(ctx, authz.ActionRead, principal.Subject) == nil
+}
@@ -1,12 +1,15 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/t5129001t-jpg/subscription-service/internal/authz"
 	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/problem"
 	"github.com/t5129001t-jpg/subscription-service/internal/service"
 )
 
@@ -21,13 +24,13 @@ func NewSubscriptionHandler(service service.SubscriptionService) *SubscriptionHa
 func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
 	var req model.CreateSubscriptionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithBindingError(c, err, &req)
 		return
 	}
 
-	sub, err := h.service.Create(&req)
+	sub, err := h.service.Create(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithServiceError(c, err)
 		return
 	}
 
@@ -37,17 +40,17 @@ func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
 func (h *SubscriptionHandler) GetSubscription(c *gin.Context) {
 	id := c.Param("id")
 	if _, err := uuid.Parse(id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID format"})
+		abortWithInvalidField(c, "path.id", "invalid UUID format")
 		return
 	}
 
-	sub, err := h.service.GetByID(id)
+	sub, err := h.service.GetByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithServiceError(c, err)
 		return
 	}
 	if sub == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+		problem.Abort(c, problem.New(http.StatusNotFound, "subscription not found"))
 		return
 	}
 
@@ -57,41 +60,61 @@ func (h *SubscriptionHandler) GetSubscription(c *gin.Context) {
 func (h *SubscriptionHandler) UpdateSubscription(c *gin.Context) {
 	id := c.Param("id")
 	if _, err := uuid.Parse(id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID format"})
+		abortWithInvalidField(c, "path.id", "invalid UUID format")
 		return
 	}
 
 	var req model.UpdateSubscriptionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithBindingError(c, err, &req)
 		return
 	}
 
-	err := h.service.Update(id, &req)
+	warning, err := h.service.Update(c.Request.Context(), id, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithServiceError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "subscription updated successfully"})
+	response := gin.H{"message": "subscription updated successfully"}
+	if warning != nil {
+		response["budget_warning"] = warning
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
 	id := c.Param("id")
 	if _, err := uuid.Parse(id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID format"})
+		abortWithInvalidField(c, "path.id", "invalid UUID format")
 		return
 	}
 
-	err := h.service.Delete(id)
+	err := h.service.Delete(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithServiceError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "subscription deleted successfully"})
 }
 
+func (h *SubscriptionHandler) RestoreSubscription(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		abortWithInvalidField(c, "path.id", "invalid UUID format")
+		return
+	}
+
+	sub, err := h.service.Restore(c.Request.Context(), id)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
 func (h *SubscriptionHandler) ListSubscriptions(c *gin.Context) {
 	var filter model.SubscriptionFilter
 	
@@ -100,6 +123,11 @@ func (h *SubscriptionHandler) ListSubscriptions(c *gin.Context) {
 	filter.Month = c.Query("month")
 	filter.StartMonth = c.Query("start_month")
 	filter.EndMonth = c.Query("end_month")
+	filter.Status = c.Query("status")
+	if filter.Status != "" && !model.IsValidSubscriptionStatus(filter.Status) {
+		abortWithInvalidField(c, "query.status", "invalid status")
+		return
+	}
 	
 	if limit, err := strconv.Atoi(c.DefaultQuery("limit", "10")); err == nil {
 		filter.Limit = limit
@@ -109,9 +137,9 @@ func (h *SubscriptionHandler) ListSubscriptions(c *gin.Context) {
 		filter.Offset = offset
 	}
 
-	subscriptions, total, err := h.service.List(filter)
+	subscriptions, total, err := h.service.List(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithServiceError(c, err)
 		return
 	}
 
@@ -133,17 +161,278 @@ func (h *SubscriptionHandler) GetTotalPrice(c *gin.Context) {
 	filter.EndMonth = c.Query("end_month")
 
 	if filter.Month == "" && (filter.StartMonth == "" || filter.EndMonth == "") {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "either 'month' or both 'start_month' and 'end_month' must be provided",
-		})
+		problem.Abort(c, problem.New(http.StatusBadRequest,
+			"either 'month' or both 'start_month' and 'end_month' must be provided"))
 		return
 	}
 
-	total, err := h.service.GetTotalPrice(filter)
+	total, err := h.service.GetTotalPrice(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithServiceError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"total_price": total})
 }
+
+func (h *SubscriptionHandler) PauseSubscription(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		abortWithInvalidField(c, "path.id", "invalid UUID format")
+		return
+	}
+
+	var req model.PauseSubscriptionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithBindingError(c, err, &req)
+			return
+		}
+	}
+
+	sub, err := h.service.Pause(c.Request.Context(), id, &req)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+func (h *SubscriptionHandler) ResumeSubscription(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		abortWithInvalidField(c, "path.id", "invalid UUID format")
+		return
+	}
+
+	var req model.ResumeSubscriptionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithBindingError(c, err, &req)
+			return
+		}
+	}
+
+	sub, err := h.service.Resume(c.Request.Context(), id, &req)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+func (h *SubscriptionHandler) ChangeSubscriptionStatus(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		abortWithInvalidField(c, "path.id", "invalid UUID format")
+		return
+	}
+
+	var req model.ChangeStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindingError(c, err, &req)
+		return
+	}
+
+	sub, err := h.service.ChangeStatus(c.Request.Context(), id, &req)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+func (h *SubscriptionHandler) CancelSubscription(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		abortWithInvalidField(c, "path.id", "invalid UUID format")
+		return
+	}
+
+	var req model.CancelSubscriptionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithBindingError(c, err, &req)
+			return
+		}
+	}
+
+	sub, err := h.service.Cancel(c.Request.Context(), id, &req)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+func (h *SubscriptionHandler) ListUpcomingRenewals(c *gin.Context) {
+	userID := c.Param("user_id")
+	if _, err := uuid.Parse(userID); err != nil {
+		abortWithInvalidField(c, "path.user_id", "invalid UUID format")
+		return
+	}
+
+	withinDays, err := strconv.Atoi(c.DefaultQuery("within_days", "30"))
+	if err != nil {
+		abortWithInvalidField(c, "query.within_days", "within_days must be an integer")
+		return
+	}
+
+	renewals, err := h.service.UpcomingRenewals(c.Request.Context(), userID, withinDays)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	totalAmount := 0
+	for _, renewal := range renewals {
+		totalAmount += renewal.Amount
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":         renewals,
+		"total_amount": totalAmount,
+		"within_days":  withinDays,
+	})
+}
+
+func (h *SubscriptionHandler) ListOverlaps(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID != "" {
+		if _, err := uuid.Parse(userID); err != nil {
+			abortWithInvalidField(c, "query.user_id", "invalid UUID format")
+			return
+		}
+	}
+
+	overlaps, err := h.service.ListOverlaps(c.Request.Context(), userID)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": overlaps})
+}
+
+func (h *SubscriptionHandler) ListMembers(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		abortWithInvalidField(c, "path.id", "invalid UUID format")
+		return
+	}
+
+	members, err := h.service.ListMembers(c.Request.Context(), id)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": members})
+}
+
+func (h *SubscriptionHandler) SetMember(c *gin.Context) {
+	id := c.Param("id")
+	userID := c.Param("user_id")
+	if _, err := uuid.Parse(id); err != nil {
+		abortWithInvalidField(c, "path.id", "invalid UUID format")
+		return
+	}
+	if _, err := uuid.Parse(userID); err != nil {
+		abortWithInvalidField(c, "path.user_id", "invalid UUID format")
+		return
+	}
+
+	var req model.SetMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindingError(c, err, &req)
+		return
+	}
+
+	member, err := h.service.SetMember(c.Request.Context(), id, userID, &req)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+func (h *SubscriptionHandler) RemoveMember(c *gin.Context) {
+	id := c.Param("id")
+	userID := c.Param("user_id")
+	if _, err := uuid.Parse(id); err != nil {
+		abortWithInvalidField(c, "path.id", "invalid UUID format")
+		return
+	}
+	if _, err := uuid.Parse(userID); err != nil {
+		abortWithInvalidField(c, "path.user_id", "invalid UUID format")
+		return
+	}
+
+	if err := h.service.RemoveMember(c.Request.Context(), id, userID); err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "member removed successfully"})
+}
+
+// abortWithServiceError answers an error of the subscription service.
+// Duplicate errors name the conflicting subscription so that clients can
+// offer to edit it instead.
+func abortWithServiceError(c *gin.Context, err error) {
+	status := serviceErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		abortWithUnexpected(c, err)
+		return
+	}
+
+	p := problem.New(status, err.Error())
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		p.Errors = []problem.FieldError{{Field: fieldLocation(c, validationErr.Field), Message: validationErr.Message}}
+	}
+	var duplicateErr *service.DuplicateSubscriptionError
+	if errors.As(err, &duplicateErr) {
+		p.Extensions = map[string]any{"conflicting_id": duplicateErr.ConflictingID}
+	}
+	problem.Abort(c, p)
+}
+
+// fieldLocation prefixes field with where the request gave it: the path or
+// the query when it has such a parameter, otherwise the body.
+func fieldLocation(c *gin.Context, field string) string {
+	if _, ok := c.Params.Get(field); ok {
+		return "path." + field
+	}
+	if c.Request.URL.Query().Has(field) {
+		return "query." + field
+	}
+	return "body." + field
+}
+
+func serviceErrorStatus(err error) int {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrSubscriptionNotFound),
+		errors.Is(err, service.ErrMemberNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrAlreadyPaused),
+		errors.Is(err, service.ErrNotPaused),
+		errors.Is(err, service.ErrInvalidTransition),
+		errors.Is(err, service.ErrDuplicateSubscription):
+		return http.StatusConflict
+	case errors.Is(err, authz.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrBudgetExceeded):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
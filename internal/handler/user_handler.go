@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/t5129001t-jpg/subscription-service/internal/authz"
+	"github.com/t5129001t-jpg/subscription-service/internal/middleware"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
+)
+
+type UserHandler struct {
+	service    service.UserService
+	authorizer authz.Authorizer
+}
+
+// NewUserHandler lets callers the authorizer allows to write any user
+// register users; everyone else may only register themselves. authorizer
+// is nil when authentication is disabled.
+func NewUserHandler(service service.UserService, authorizer authz.Authorizer) *UserHandler {
+	return &UserHandler{service: service, authorizer: authorizer}
+}
+
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var req model.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if principal := middleware.PrincipalFrom(c); principal != nil && !h.canWriteAnyUser(c) {
+		if req.ID != nil && *req.ID != principal.Subject {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		req.ID = &principal.Subject
+	}
+
+	user, err := h.service.Create(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+func (h *UserHandler) canWriteAnyUser(c *gin.Context) bool {
+	return h.authorizer == nil || h.authorizer.Authorize(c.Request.Context(), authz.ActionUsersWrite, "") == nil
+}
+
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	// Report the page actually listed, not the one asked for.
+	limit, offset = service.Page(limit, offset)
+
+	users, total, err := h.service.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   users,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *UserHandler) GetUser(c *gin.Context) {
+	id := c.Param("user_id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID format"})
+		return
+	}
+
+	user, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	id := c.Param("user_id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID format"})
+		return
+	}
+
+	var req model.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.service.Update(c.Request.Context(), id, &req)
+	if err != nil {
+		c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id := c.Param("user_id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID format"})
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "user deleted successfully"})
+}
+
+func (h *UserHandler) GetUserSummary(c *gin.Context) {
+	id := c.Param("user_id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID format"})
+		return
+	}
+
+	summary, err := h.service.GetSummary(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// ExportUserData serves everything stored about the user as a JSON file.
+func (h *UserHandler) ExportUserData(c *gin.Context) {
+	id := c.Param("user_id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID format"})
+		return
+	}
+
+	export, err := h.service.Export(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s-export.json"`, id))
+	c.IndentedJSON(http.StatusOK, export)
+}
+
+// EraseUserData erases the user's data in the current tenant. Admins may
+// erase it from every tenant at once with tenants=all.
+func (h *UserHandler) EraseUserData(c *gin.Context) {
+	id := c.Param("user_id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID format"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if c.Query("tenants") == "all" {
+		if principal := middleware.PrincipalFrom(c); principal != nil && !principal.Admin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		ctx = tenant.AllTenants(ctx)
+	}
+
+	receipt, err := h.service.EraseData(ctx, id, c.Query("mode"))
+	if err != nil {
+		c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, receipt)
+}
+
+func userErrorStatus(err error) int {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrUserExists),
+		errors.Is(err, service.ErrUserHasSubscriptions):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
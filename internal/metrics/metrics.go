@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "subscription_service"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	QueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "repository_query_duration_seconds",
+		Help:      "Duration of repository methods, including their transactions.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"repository", "method"})
+)
+
+// ObserveQuery records the duration of a repository method since started.
+// It is meant to be deferred:
+//
+//	defer metrics.ObserveQuery("subscriptions", "List", time.Now())
+func ObserveQuery(repository, method string, started time.Time) {
+	QueryDuration.WithLabelValues(repository, method).Observe(time.Since(started).Seconds())
+}
+
+// SubscriptionCounter counts subscriptions of every tenant by tenant and
+// effective status.
+type SubscriptionCounter interface {
+	CountByStatus(ctx context.Context) (map[string]map[string]int, error)
+}
+
+var subscriptionsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "subscriptions"),
+	"Subscriptions by tenant and effective status.",
+	[]string{"tenant", "status"}, nil,
+)
+
+type subscriptionCollector struct {
+	counter SubscriptionCounter
+	timeout time.Duration
+}
+
+// NewSubscriptionCollector reports the subscription counts on each scrape.
+// A scrape during which counting fails reports none.
+func NewSubscriptionCollector(counter SubscriptionCounter, timeout time.Duration) prometheus.Collector {
+	return &subscriptionCollector{counter: counter, timeout: timeout}
+}
+
+func (c *subscriptionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- subscriptionsDesc
+}
+
+func (c *subscriptionCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	counts, err := c.counter.CountByStatus(ctx)
+	if err != nil {
+		slog.Error("failed to count subscriptions for metrics", "error", err)
+		return
+	}
+	for tenantID, byStatus := range counts {
+		for status, count := range byStatus {
+			ch <- prometheus.MustNewConstMetric(subscriptionsDesc, prometheus.GaugeValue, float64(count), tenantID, status)
+		}
+	}
+}
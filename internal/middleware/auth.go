@@ -0,0 +1,316 @@
+package middleware
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/t5129001t-jpg/subscription-service/internal/authz"
+	"github.com/t5129001t-jpg/subscription-service/internal/config"
+	"github.com/t5129001t-jpg/subscription-service/internal/logging"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
+)
+
+type verificationKey struct {
+	id  string
+	alg string
+	key interface{}
+}
+
+// JWTVerifier checks HS256 and RS256 bearer tokens and turns their claims
+// into a Principal.
+type JWTVerifier struct {
+	keys        []verificationKey
+	parser      *jwt.Parser
+	rolesClaim  string
+	adminRole   string
+	tenantClaim string
+}
+
+// minHS256SecretLen is the shortest HS256 secret accepted: 256 bits, the
+// size of the HMAC-SHA256 output.
+const minHS256SecretLen = 32
+
+// placeholderHS256Secret is the example secret once shipped in .env.example.
+// Anyone can sign tokens with it, so it is refused like a short secret.
+const placeholderHS256Secret = "change-me-to-a-long-random-secret"
+
+// NewJWTVerifier loads the keys named by cfg. At least one key is required,
+// and an HS256 secret must be at least 32 bytes and not the placeholder.
+func NewJWTVerifier(cfg config.AuthConfig) (*JWTVerifier, error) {
+	v := &JWTVerifier{rolesClaim: cfg.RolesClaim, adminRole: cfg.AdminRole, tenantClaim: cfg.TenantClaim}
+
+	if cfg.HS256Secret != "" {
+		if cfg.HS256Secret == placeholderHS256Secret {
+			return nil, errors.New("JWT_HS256_SECRET is the example placeholder; set a random secret")
+		}
+		if len(cfg.HS256Secret) < minHS256SecretLen {
+			return nil, fmt.Errorf("JWT_HS256_SECRET must be at least %d bytes", minHS256SecretLen)
+		}
+		v.keys = append(v.keys, verificationKey{alg: "HS256", key: []byte(cfg.HS256Secret)})
+	}
+	if cfg.RS256PublicKeyFile != "" {
+		pem, err := os.ReadFile(cfg.RS256PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("parse RS256 public key: %w", err)
+		}
+		v.keys = append(v.keys, verificationKey{alg: "RS256", key: key})
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("load JWKS: %w", err)
+		}
+		v.keys = append(v.keys, keys...)
+	}
+	if len(v.keys) == 0 {
+		return nil, errors.New("no JWT verification keys configured")
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "RS256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(options...)
+
+	return v, nil
+}
+
+// Verify validates token and returns its principal. The token must carry
+// a subject, and its tenant claim, if any, must be a valid tenant id.
+func (v *JWTVerifier) Verify(token string) (*model.Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, v.keyFunc); err != nil {
+		return nil, err
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil {
+		return nil, err
+	}
+	if subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+
+	principal := &model.Principal{Subject: subject, Roles: rolesFromClaim(claims[v.rolesClaim])}
+	for _, role := range principal.Roles {
+		if role == v.adminRole {
+			principal.Admin = true
+		}
+	}
+
+	if claim, ok := claims[v.tenantClaim]; ok && v.tenantClaim != "" {
+		id, _ := claim.(string)
+		if !tenant.IsValidID(id) {
+			return nil, errors.New("token has an invalid tenant")
+		}
+		principal.Tenant = id
+	}
+	return principal, nil
+}
+
+// keyFunc offers every key for the token's algorithm, narrowed to the
+// token's key id when it has one.
+func (v *JWTVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	set := jwt.VerificationKeySet{}
+	for _, key := range v.keys {
+		if key.alg != token.Method.Alg() {
+			continue
+		}
+		if kid != "" && key.id != "" && key.id != kid {
+			continue
+		}
+		set.Keys = append(set.Keys, key.key)
+	}
+	if len(set.Keys) == 0 {
+		return nil, fmt.Errorf("no key for algorithm %s", token.Method.Alg())
+	}
+	return set, nil
+}
+
+// rolesFromClaim accepts roles as a JSON array or a space-separated string.
+func rolesFromClaim(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		roles := make([]string, 0, len(value))
+		for _, role := range value {
+			if s, ok := role.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+		return roles
+	}
+	return nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// loadJWKS reads the RSA and symmetric keys of a JSON Web Key Set file.
+// Keys of other types are skipped.
+func loadJWKS(path string) ([]verificationKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make([]verificationKey, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		switch jwk.Kty {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", jwk.Kid, err)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", jwk.Kid, err)
+			}
+			key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+			keys = append(keys, verificationKey{id: jwk.Kid, alg: "RS256", key: key})
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(jwk.K)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", jwk.Kid, err)
+			}
+			keys = append(keys, verificationKey{id: jwk.Kid, alg: "HS256", key: secret})
+		}
+	}
+	return keys, nil
+}
+
+// Authenticate requires a valid bearer token or, when apiKeys is set, an
+// API key given as "Authorization: ApiKey <key>", and stores the caller's
+// principal in the context.
+func Authenticate(verifier *JWTVerifier, apiKeys service.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, credentials, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		credentials = strings.TrimSpace(credentials)
+
+		var principal *model.Principal
+		var err error
+		switch {
+		case scheme == "Bearer" && credentials != "":
+			principal, err = verifier.Verify(credentials)
+			if err != nil {
+				c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+				return
+			}
+		case scheme == "ApiKey" && credentials != "" && apiKeys != nil:
+			principal, err = apiKeys.Authenticate(c.Request.Context(), credentials)
+			if errors.Is(err, service.ErrInvalidAPIKey) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+				return
+			}
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		default:
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token or api key"})
+			return
+		}
+
+		ctx := authz.WithPrincipal(c.Request.Context(), principal)
+		c.Request = c.Request.WithContext(logging.With(ctx, "user_id", principal.Subject))
+		c.Next()
+	}
+}
+
+// PrincipalFrom returns the caller of the request, or nil when
+// authentication is disabled.
+func PrincipalFrom(c *gin.Context) *model.Principal {
+	return authz.PrincipalFrom(c.Request.Context())
+}
+
+// RequireAdmin lets only admins through.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal := PrincipalFrom(c); principal != nil && !principal.Admin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Authorize asks authorizer whether the caller may perform read, for GET
+// and HEAD requests, or write on the data of the user named by the path
+// parameter ownerParam. Without ownerParam the data of every user is
+// asked for. Nothing is checked when authentication is disabled.
+func Authorize(authorizer authz.Authorizer, read, write, ownerParam string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authorizer == nil || PrincipalFrom(c) == nil {
+			c.Next()
+			return
+		}
+		action := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			action = read
+		}
+		ownerID := ""
+		if ownerParam != "" {
+			ownerID = c.Param(ownerParam)
+		}
+		if err := authorizer.Authorize(c.Request.Context(), action, ownerID); err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScopes checks the caller's scopes: read for GET and HEAD
+// requests, write for everything else.
+func RequireScopes(read, write string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = read
+		}
+		if principal := PrincipalFrom(c); principal != nil && !principal.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing scope " + scope})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/t5129001t-jpg/subscription-service/internal/logging"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// Idempotency makes requests carrying an Idempotency-Key safe to retry: the
+// first response is stored and replayed for later requests with the same
+// key and body, marked with Idempotent-Replayed. Reusing a key with a
+// different body gets 422, and a retry while the first request is still
+// running gets 409. Keys are scoped to the caller and route. Server errors
+// are not stored, so the request can be retried for real.
+func Idempotency(svc service.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := idempotencyScope(c)
+		hash := sha256.Sum256(body)
+		stored, err := svc.Begin(c.Request.Context(), scope, key, hex.EncodeToString(hash[:]))
+		switch {
+		case errors.Is(err, service.ErrInvalidIdempotencyKey):
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, service.ErrIdempotencyKeyReused):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check idempotency key"})
+			return
+		}
+
+		if stored != nil {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(stored.StatusCode, stored.ContentType, stored.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		if status := recorder.Status(); status >= http.StatusInternalServerError {
+			if err := svc.Release(c.Request.Context(), scope, key); err != nil {
+				logging.FromContext(c.Request.Context()).Error("failed to release idempotency key", "error", err)
+			}
+		} else {
+			response := &model.StoredResponse{
+				StatusCode:  status,
+				ContentType: recorder.Header().Get("Content-Type"),
+				Body:        recorder.body.Bytes(),
+			}
+			if err := svc.Complete(c.Request.Context(), scope, key, response); err != nil {
+				logging.FromContext(c.Request.Context()).Error("failed to store idempotent response", "error", err)
+			}
+		}
+	}
+}
+
+// idempotencyScope keeps one caller's keys apart from another's, and one
+// route's from another's.
+func idempotencyScope(c *gin.Context) string {
+	tenantID, _ := tenant.FromContext(c.Request.Context())
+	return tenantID + "|" + clientKey(c) + "|" + c.Request.Method + " " + c.FullPath()
+}
+
+// responseRecorder copies the response body as it is written.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/t5129001t-jpg/subscription-service/internal/config"
+	"github.com/t5129001t-jpg/subscription-service/internal/logging"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+)
+
+// RateLimiter limits each client per route group. A nil RateLimiter lets
+// everything through.
+type RateLimiter struct {
+	store  service.RateLimitStore
+	limits map[string]config.RateLimit
+}
+
+func NewRateLimiter(store service.RateLimitStore, limits map[string]config.RateLimit) *RateLimiter {
+	return &RateLimiter{store: store, limits: limits}
+}
+
+// Limit applies group's limit, per API key, user or, for anonymous
+// requests, client IP. Rejected requests get 429 with Retry-After; every
+// response carries the RateLimit-* headers. Groups without a positive
+// limit are not limited, and requests are let through when the store
+// fails.
+func (l *RateLimiter) Limit(group string) gin.HandlerFunc {
+	if l == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	limit, ok := l.limits[group]
+	if !ok || limit.PerMinute <= 0 || limit.Burst <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	policy := strconv.Itoa(limit.PerMinute) + ";w=60;burst=" + strconv.Itoa(limit.Burst)
+
+	return func(c *gin.Context) {
+		decision, err := l.store.Take(c.Request.Context(), group+":"+clientKey(c), limit)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("rate limiting failed, letting request through", "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		c.Header("RateLimit-Reset", seconds(decision.Reset))
+		if !decision.Allowed {
+			c.Header("Retry-After", seconds(decision.RetryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// LimitFailedAuth limits, per client IP, requests that authentication
+// rejects, and goes in front of it. Once an IP has used up group's limit
+// on rejected requests, all of its requests get 429 with Retry-After until
+// the bucket refills; accepted requests cost nothing. A group without a
+// positive limit is not limited, and requests are let through when the
+// store fails.
+func (l *RateLimiter) LimitFailedAuth(group string) gin.HandlerFunc {
+	if l == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	limit, ok := l.limits[group]
+	if !ok || limit.PerMinute <= 0 || limit.Burst <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		key := group + ":ip:" + c.ClientIP()
+		logger := logging.FromContext(c.Request.Context())
+
+		decision, err := l.store.Peek(c.Request.Context(), key, limit)
+		if err != nil {
+			logger.Error("rate limiting failed, letting request through", "error", err)
+		} else if !decision.Allowed {
+			c.Header("Retry-After", seconds(decision.RetryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many failed authentication attempts"})
+			return
+		}
+
+		c.Next()
+
+		if c.Writer.Status() == http.StatusUnauthorized {
+			if _, err := l.store.Take(c.Request.Context(), key, limit); err != nil {
+				logger.Error("failed to count rejected authentication", "error", err)
+			}
+		}
+	}
+}
+
+// clientKey identifies the caller: an API key by its "api-key:" subject, a
+// user by theirs, and anyone else by IP.
+func clientKey(c *gin.Context) string {
+	principal := PrincipalFrom(c)
+	switch {
+	case principal == nil:
+		return "ip:" + c.ClientIP()
+	case strings.HasPrefix(principal.Subject, "api-key:"):
+		return principal.Subject
+	default:
+		return "user:" + principal.Subject
+	}
+}
+
+// seconds rounds d up to whole seconds, as header values.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/t5129001t-jpg/subscription-service/internal/config"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
+)
+
+// ResolveTenant stores the request's tenant in the context. A tenant claim
+// in the caller's token, or the tenant an API key was issued in, decides
+// it. Otherwise admins and, with authentication disabled, anyone may
+// choose one with the tenant header; everyone else gets the default
+// tenant. Asking for another tenant is answered with 403.
+func ResolveTenant(cfg config.TenantConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requested := c.GetHeader(cfg.Header)
+		if requested != "" && !tenant.IsValidID(requested) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid tenant"})
+			return
+		}
+
+		id := cfg.Default
+		principal := PrincipalFrom(c)
+		if principal != nil && principal.Tenant != "" {
+			id = principal.Tenant
+		} else if requested != "" && (principal == nil || principal.Admin) {
+			id = requested
+		}
+		if requested != "" && requested != id {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden tenant"})
+			return
+		}
+
+		c.Request = c.Request.WithContext(tenant.WithID(c.Request.Context(), id))
+		c.Next()
+	}
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"errors"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/t5129001t-jpg/subscription-service/docs"
+	"github.com/t5129001t-jpg/subscription-service/internal/problem"
+)
+
+// ValidateRequest checks the path parameters, query and body of each
+// request against its operation in spec, and answers 400 with a problem
+// listing every invalid field. Routes missing from spec are not checked.
+// Authentication is left to Authenticate.
+func ValidateRequest(spec *openapi3.T) gin.HandlerFunc {
+	defineFormats()
+	options := &openapi3filter.Options{
+		MultiError:          true,
+		AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+		SkipSettingDefaults: true,
+	}
+
+	return func(c *gin.Context) {
+		path := docs.PathFromRoute(c.FullPath())
+		item := spec.Paths.Value(path)
+		if item == nil || item.GetOperation(c.Request.Method) == nil {
+			c.Next()
+			return
+		}
+
+		params := make(map[string]string, len(c.Params))
+		for _, param := range c.Params {
+			params[param.Key] = param.Value
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: params,
+			Options:    options,
+			Route: &routers.Route{
+				Spec:      spec,
+				Path:      path,
+				PathItem:  item,
+				Method:    c.Request.Method,
+				Operation: item.GetOperation(c.Request.Method),
+			},
+		}
+
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			problem.Abort(c, problem.Invalid(fieldErrors(err)...))
+			return
+		}
+		c.Next()
+	}
+}
+
+// defineFormats makes the formats the document uses checked the way the
+// handlers and binding tags check them.
+func defineFormats() {
+	openapi3.DefineStringFormatCallback("uuid", func(value string) error {
+		_, err := uuid.Parse(value)
+		return err
+	})
+	openapi3.DefineStringFormatCallback("email", func(value string) error {
+		_, err := mail.ParseAddress(value)
+		return err
+	})
+	openapi3.DefineStringFormatCallback("uri", func(value string) error {
+		u, err := url.Parse(value)
+		if err == nil && u.Scheme == "" {
+			err = errors.New("not an absolute URL")
+		}
+		return err
+	})
+}
+
+// fieldErrors flattens the errors of a failed validation into one entry
+// per invalid field. openapi3.MultiError is taken apart by type rather
+// than with errors.As, which would only find its first error.
+func fieldErrors(err error) []problem.FieldError {
+	switch err := err.(type) {
+	case openapi3.MultiError:
+		var fields []problem.FieldError
+		for _, err := range err {
+			fields = append(fields, fieldErrors(err)...)
+		}
+		return fields
+	case *openapi3filter.RequestError:
+		if param := err.Parameter; param != nil {
+			return []problem.FieldError{{Field: param.In + "." + param.Name, Message: reason(err)}}
+		}
+		return bodyErrors(err, err.Err)
+	default:
+		return []problem.FieldError{{Field: "request", Message: err.Error()}}
+	}
+}
+
+func bodyErrors(requestErr *openapi3filter.RequestError, err error) []problem.FieldError {
+	switch err := err.(type) {
+	case openapi3.MultiError:
+		var fields []problem.FieldError
+		for _, err := range err {
+			fields = append(fields, bodyErrors(requestErr, err)...)
+		}
+		return fields
+	case *openapi3.SchemaError:
+		return []problem.FieldError{{
+			Field:   strings.Join(append([]string{"body"}, err.JSONPointer()...), "."),
+			Message: err.Reason,
+		}}
+	default:
+		return []problem.FieldError{{Field: "body", Message: reason(requestErr)}}
+	}
+}
+
+// reason describes err without repeating which field it is about.
+func reason(err *openapi3filter.RequestError) string {
+	var schemaErr *openapi3.SchemaError
+	switch {
+	case errors.As(err.Err, &schemaErr):
+		return schemaErr.Reason
+	case err.Err == nil:
+		return err.Reason
+	case err.Reason == "":
+		return err.Err.Error()
+	default:
+		return err.Reason + ": " + err.Err.Error()
+	}
+}
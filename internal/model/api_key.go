@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	ScopeSubscriptionsRead  = "subscriptions:read"
+	ScopeSubscriptionsWrite = "subscriptions:write"
+	ScopeUsersRead          = "users:read"
+	ScopeUsersWrite         = "users:write"
+	ScopeWebhooksRead       = "webhooks:read"
+	ScopeWebhooksWrite      = "webhooks:write"
+	// ScopeAPIKeysManage is never granted to API keys, so that keys cannot
+	// issue other keys.
+	ScopeAPIKeysManage = "api_keys:manage"
+)
+
+// APIKey is a credential for service-to-service clients. Only a hash of
+// the key is stored; Prefix identifies it in listings.
+type APIKey struct {
+	ID         string         `json:"id" db:"id"`
+	TenantID   string         `json:"tenant_id" db:"tenant_id"`
+	Name       string         `json:"name" db:"name"`
+	Prefix     string         `json:"prefix" db:"prefix"`
+	KeyHash    string         `json:"-" db:"key_hash"`
+	Scopes     pq.StringArray `json:"scopes" db:"scopes"`
+	ExpiresAt  *time.Time     `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time     `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=255"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=subscriptions:read subscriptions:write users:read users:write webhooks:read webhooks:write"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// IssuedAPIKey carries the plain key, which is shown only once.
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	BudgetEnforcementWarn   = "warn"
+	BudgetEnforcementReject = "reject"
+)
+
+type Budget struct {
+	UserID       string    `json:"user_id" db:"user_id"`
+	TenantID     string    `json:"tenant_id" db:"tenant_id"`
+	MonthlyLimit int       `json:"monthly_limit" db:"monthly_limit"`
+	Enforcement  string    `json:"enforcement" db:"enforcement"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type SetBudgetRequest struct {
+	MonthlyLimit *int    `json:"monthly_limit" binding:"required,min=0"`
+	Enforcement  *string `json:"enforcement,omitempty" binding:"omitempty,oneof=warn reject"`
+}
+
+type BudgetStatus struct {
+	UserID       string `json:"user_id"`
+	Month        string `json:"month"`
+	MonthlyLimit int    `json:"monthly_limit"`
+	Spend        int    `json:"spend"`
+	Remaining    int    `json:"remaining"`
+	Exceeded     bool   `json:"exceeded"`
+	Enforcement  string `json:"enforcement"`
+}
+
+// BudgetWarning describes the overspend a subscription change would cause.
+type BudgetWarning struct {
+	Month          string `json:"month"`
+	MonthlyLimit   int    `json:"monthly_limit"`
+	ProjectedSpend int    `json:"projected_spend"`
+	ExceededBy     int    `json:"exceeded_by"`
+}
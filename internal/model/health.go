@@ -0,0 +1,8 @@
+package model
+
+// Readiness reports whether the service can take traffic, with the outcome
+// of each check: "ok" or what failed.
+type Readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// IdempotencyRecord remembers a request made with an Idempotency-Key and,
+// once handled, its response. Scope keeps keys of different clients and
+// endpoints apart.
+type IdempotencyRecord struct {
+	Scope        string    `db:"scope"`
+	Key          string    `db:"key"`
+	RequestHash  string    `db:"request_hash"`
+	StatusCode   *int      `db:"status_code"`
+	ContentType  *string   `db:"content_type"`
+	ResponseBody []byte    `db:"response_body"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+// StoredResponse is a response kept for replay.
+type StoredResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
@@ -0,0 +1,24 @@
+package model
+
+// Principal is the authenticated caller of a request. Scopes limit what
+// an API key may do; a nil Scopes places no limit. Tenant is set for
+// callers bound to one tenant by their token.
+type Principal struct {
+	Subject string
+	Roles   []string
+	Admin   bool
+	Scopes  []string
+	Tenant  string
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	if p.Scopes == nil {
+		return true
+	}
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -4,16 +4,41 @@ import (
 	"time"
 )
 
+const (
+	SubscriptionStatusTrial     = "trial"
+	SubscriptionStatusActive    = "active"
+	SubscriptionStatusPaused    = "paused"
+	SubscriptionStatusCancelled = "cancelled"
+	SubscriptionStatusExpired   = "expired"
+)
+
+func IsValidSubscriptionStatus(status string) bool {
+	switch status {
+	case SubscriptionStatusTrial, SubscriptionStatusActive, SubscriptionStatusPaused,
+		SubscriptionStatusCancelled, SubscriptionStatusExpired:
+		return true
+	}
+	return false
+}
+
 type Subscription struct {
-	ID          string     `json:"id" db:"id"`
-	ServiceName string     `json:"service_name" db:"service_name" binding:"required"`
-	Price       int        `json:"price" db:"price" binding:"required,min=0"`
-	UserID      string     `json:"user_id" db:"user_id" binding:"required,uuid"`
-	StartDate   string     `json:"start_date" db:"start_date" binding:"required"`
-	EndDate     *string    `json:"end_date,omitempty" db:"end_date"`
-	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
-	DeletedAt   *time.Time `json:"-" db:"deleted_at"`
+	ID                 string         `json:"id" db:"id"`
+	TenantID           string         `json:"tenant_id" db:"tenant_id"`
+	ServiceName        string         `json:"service_name" db:"service_name" binding:"required"`
+	Price              int            `json:"price" db:"price" binding:"required,min=0"`
+	UserID             string         `json:"user_id" db:"user_id" binding:"required,uuid"`
+	StartDate          string         `json:"start_date" db:"start_date" binding:"required"`
+	EndDate            *string        `json:"end_date,omitempty" db:"end_date"`
+	TrialEnd           *string        `json:"trial_end,omitempty" db:"trial_end"`
+	BillingDay         int            `json:"billing_day" db:"billing_day"`
+	Status             string         `json:"status" db:"status"`
+	CancellationReason *string        `json:"cancellation_reason,omitempty" db:"cancellation_reason"`
+	CancelledAt        *time.Time     `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	UserShare          *int           `json:"user_share,omitempty" db:"user_share"`
+	BudgetWarning      *BudgetWarning `json:"budget_warning,omitempty" db:"-"`
+	CreatedAt          time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at" db:"updated_at"`
+	DeletedAt          *time.Time     `json:"-" db:"deleted_at"`
 }
 
 type CreateSubscriptionRequest struct {
@@ -22,14 +47,110 @@ type CreateSubscriptionRequest struct {
 	UserID      string  `json:"user_id" binding:"required,uuid"`
 	StartDate   string  `json:"start_date" binding:"required"`
 	EndDate     *string `json:"end_date,omitempty"`
+	TrialEnd    *string `json:"trial_end,omitempty"`
+	BillingDay  *int    `json:"billing_day,omitempty" binding:"omitempty,min=1,max=31"`
+	// AllowOverlap skips the check for an overlapping subscription of the
+	// same user to the same service.
+	AllowOverlap bool `json:"allow_overlap,omitempty"`
 }
 
 type UpdateSubscriptionRequest struct {
-	ServiceName *string `json:"service_name,omitempty"`
-	Price       *int    `json:"price,omitempty" binding:"omitempty,min=0"`
-	UserID      *string `json:"user_id,omitempty" binding:"omitempty,uuid"`
-	StartDate   *string `json:"start_date,omitempty"`
-	EndDate     *string `json:"end_date,omitempty"`
+	ServiceName  *string `json:"service_name,omitempty"`
+	Price        *int    `json:"price,omitempty" binding:"omitempty,min=0"`
+	UserID       *string `json:"user_id,omitempty" binding:"omitempty,uuid"`
+	StartDate    *string `json:"start_date,omitempty"`
+	EndDate      *string `json:"end_date,omitempty"`
+	TrialEnd     *string `json:"trial_end,omitempty"`
+	BillingDay   *int    `json:"billing_day,omitempty" binding:"omitempty,min=1,max=31"`
+	AllowOverlap bool    `json:"allow_overlap,omitempty"`
+}
+
+type ChangeStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=trial active paused cancelled expired"`
+}
+
+type CancelSubscriptionRequest struct {
+	EffectiveMonth *string `json:"effective_month,omitempty"`
+	Reason         *string `json:"reason,omitempty" binding:"omitempty,max=1000"`
+}
+
+const (
+	ShareTypePercent = "percent"
+	ShareTypeFixed   = "fixed"
+)
+
+// SubscriptionMember is a user sharing the cost of another user's
+// subscription. The owner pays whatever the members' shares leave.
+type SubscriptionMember struct {
+	SubscriptionID string    `json:"subscription_id" db:"subscription_id"`
+	UserID         string    `json:"user_id" db:"user_id"`
+	ShareType      string    `json:"share_type" db:"share_type"`
+	ShareValue     int       `json:"share_value" db:"share_value"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Amount is the member's part of price.
+func (m *SubscriptionMember) Amount(price int) int {
+	if m.ShareType == ShareTypePercent {
+		return price * m.ShareValue / 100
+	}
+	return min(m.ShareValue, price)
+}
+
+type SetMemberRequest struct {
+	ShareType  string `json:"share_type" binding:"required,oneof=percent fixed"`
+	ShareValue *int   `json:"share_value" binding:"required,min=0"`
+}
+
+type SubscriptionPause struct {
+	ID             string    `json:"id" db:"id"`
+	SubscriptionID string    `json:"subscription_id" db:"subscription_id"`
+	StartMonth     string    `json:"start_month" db:"start_month"`
+	EndMonth       *string   `json:"end_month,omitempty" db:"end_month"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type PauseSubscriptionRequest struct {
+	Month *string `json:"month,omitempty"`
+}
+
+type ResumeSubscriptionRequest struct {
+	Month *string `json:"month,omitempty"`
+}
+
+type Renewal struct {
+	SubscriptionID string `json:"subscription_id"`
+	TenantID       string `json:"tenant_id"`
+	UserID         string `json:"user_id"`
+	ServiceName    string `json:"service_name"`
+	Amount         int    `json:"amount"`
+	ChargeDate     string `json:"charge_date"`
+}
+
+// SubscriptionChange is the notification sent by the subscriptions table
+// trigger on every insert, update and delete. MemberIDs, the users the
+// subscription is shared with, only route the change and are not sent to
+// clients.
+type SubscriptionChange struct {
+	Event      string    `json:"event"`
+	ID         string    `json:"id"`
+	TenantID   string    `json:"tenant_id"`
+	UserID     string    `json:"user_id"`
+	MemberIDs  []string  `json:"member_ids,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// SubscriptionOverlap is a pair of subscriptions of one user to the same
+// service whose periods overlap. A nil OverlapEnd means open-ended.
+type SubscriptionOverlap struct {
+	UserID       string  `json:"user_id" db:"user_id"`
+	ServiceName  string  `json:"service_name" db:"service_name"`
+	FirstID      string  `json:"first_id" db:"first_id"`
+	SecondID     string  `json:"second_id" db:"second_id"`
+	OverlapStart string  `json:"overlap_start" db:"overlap_start"`
+	OverlapEnd   *string `json:"overlap_end,omitempty" db:"overlap_end"`
 }
 
 type SubscriptionFilter struct {
@@ -38,6 +159,7 @@ type SubscriptionFilter struct {
 	Month       string `form:"month"`
 	StartMonth  string `form:"start_month"`
 	EndMonth    string `form:"end_month"`
+	Status      string `form:"status"`
 	Limit       int    `form:"limit,default=10"`
 	Offset      int    `form:"offset,default=0"`
 }
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+)
+
+type User struct {
+	ID        string    `json:"id" db:"id"`
+	TenantID  string    `json:"tenant_id" db:"tenant_id"`
+	Name      string    `json:"name" db:"name"`
+	Email     *string   `json:"email,omitempty" db:"email"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CreateUserRequest struct {
+	// ID lets clients register a user_id they already use.
+	ID    *string `json:"id,omitempty" binding:"omitempty,uuid"`
+	Name  string  `json:"name" binding:"required,max=255"`
+	Email *string `json:"email,omitempty" binding:"omitempty,email,max=255"`
+}
+
+type UpdateUserRequest struct {
+	Name  *string `json:"name,omitempty" binding:"omitempty,min=1,max=255"`
+	Email *string `json:"email,omitempty" binding:"omitempty,email,max=255"`
+}
+
+// UserSummary is a user's spending at a glance. Spend counts the user's
+// share of shared subscriptions; lifetime spend runs up to the current month.
+type UserSummary struct {
+	UserID               string  `json:"user_id" db:"user_id"`
+	ActiveSubscriptions  int     `json:"active_subscriptions" db:"active_subscriptions"`
+	MonthlySpend         int     `json:"monthly_spend" db:"monthly_spend"`
+	LifetimeSpend        int     `json:"lifetime_spend" db:"lifetime_spend"`
+	MostExpensiveService *string `json:"most_expensive_service,omitempty" db:"most_expensive_service"`
+}
+
+const (
+	ErasureModeDelete    = "delete"
+	ErasureModeAnonymise = "anonymise"
+)
+
+// UserExport is everything stored about a user, deleted subscriptions and
+// their event history included.
+type UserExport struct {
+	ExportedAt    time.Time              `json:"exported_at"`
+	User          *User                  `json:"user"`
+	Subscriptions []ExportedSubscription `json:"subscriptions"`
+	Memberships   []SubscriptionMember   `json:"memberships"`
+	Budget        *Budget                `json:"budget,omitempty"`
+	Events        []OutboxEvent          `json:"events"`
+}
+
+type ExportedSubscription struct {
+	Subscription
+	DeletedAt *time.Time           `json:"deleted_at,omitempty"`
+	Pauses    []SubscriptionPause  `json:"pauses"`
+	Members   []SubscriptionMember `json:"members"`
+}
+
+// ErasureReceipt records an erasure without the user's id: SubjectHash is
+// the hex SHA-256 of it. Counts holds the number of rows removed or
+// anonymised per kind.
+type ErasureReceipt struct {
+	ID          string           `json:"id"`
+	SubjectHash string           `json:"subject_hash"`
+	Mode        string           `json:"mode"`
+	Counts      map[string]int64 `json:"counts"`
+	ErasedAt    time.Time        `json:"erased_at"`
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	EventSubscriptionCreated    = "subscription.created"
+	EventSubscriptionUpdated    = "subscription.updated"
+	EventSubscriptionDeleted    = "subscription.deleted"
+	EventSubscriptionRenewalDue = "subscription.renewal_due"
+)
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+type Webhook struct {
+	ID         string         `json:"id" db:"id"`
+	TenantID   string         `json:"tenant_id" db:"tenant_id"`
+	URL        string         `json:"url" db:"url"`
+	Secret     string         `json:"secret,omitempty" db:"secret"`
+	EventTypes pq.StringArray `json:"event_types" db:"event_types"`
+	Active     bool           `json:"active" db:"active"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at" db:"updated_at"`
+	DeletedAt  *time.Time     `json:"-" db:"deleted_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,url"`
+	Secret     *string  `json:"secret,omitempty" binding:"omitempty,min=16"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=subscription.created subscription.updated subscription.deleted subscription.renewal_due"`
+}
+
+type WebhookEvent struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type WebhookDelivery struct {
+	ID            string          `json:"id" db:"id"`
+	WebhookID     string          `json:"webhook_id" db:"webhook_id"`
+	EventType     string          `json:"event_type" db:"event_type"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	DedupeKey     *string         `json:"-" db:"dedupe_key"`
+	Status        string          `json:"status" db:"status"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     *string         `json:"last_error,omitempty" db:"last_error"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+	LeaseToken    *string         `json:"-" db:"lease_token"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at" db:"updated_at"`
+	URL           string          `json:"-" db:"url"`
+	Secret        string          `json:"-" db:"secret"`
+}
+
+type RenewalDueEvent struct {
+	Renewal
+	DaysAhead int `json:"days_ahead"`
+}
@@ -0,0 +1,72 @@
+// Package problem writes error responses as RFC 7807 problem details.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Errors lists each invalid
+// field of a request that failed validation. Extensions are further
+// members, written alongside the standard ones.
+type Problem struct {
+	Type       string         `json:"type"`
+	Title      string         `json:"title"`
+	Status     int            `json:"status"`
+	Detail     string         `json:"detail,omitempty"`
+	Instance   string         `json:"instance,omitempty"`
+	Errors     []FieldError   `json:"errors,omitempty"`
+	Extensions map[string]any `json:"-"`
+}
+
+// FieldError names an invalid field as "<location>.<name>", for example
+// "body.price" or "query.limit".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type members Problem
+	body, err := json.Marshal(members(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return body, err
+	}
+	extensions, err := json.Marshal(p.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	// Join the two objects: {...standard,...extensions}.
+	return append(append(body[:len(body)-1], ','), extensions[1:]...), nil
+}
+
+// New returns a problem of no more specific type than its status.
+func New(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Abort writes p as the response and stops the request's handler chain.
+// The problem's instance is the request path unless set.
+func Abort(c *gin.Context, p *Problem) {
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// Invalid is the problem of a request with invalid fields.
+func Invalid(errors ...FieldError) *Problem {
+	p := New(http.StatusBadRequest, "The request is invalid.")
+	p.Errors = errors
+	return p
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/t5129001t-jpg/subscription-service/internal/metrics"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
+)
+
+// APIKeyRepository methods see only the keys of the tenant in ctx, except
+// for the lookups authenticating a key, which come before the tenant is
+// known.
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *model.APIKey) error
+	List(ctx context.Context) ([]model.APIKey, error)
+	Revoke(ctx context.Context, id string) (bool, error)
+	GetByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	TouchLastUsed(ctx context.Context, id string) error
+}
+
+type apiKeyRepository struct {
+	db *sqlx.DB
+}
+
+func NewAPIKeyRepository(db *sqlx.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// Create stores key for the tenant in ctx.
+func (r *apiKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	defer metrics.ObserveQuery("api_keys", "Create", time.Now())
+
+	query := `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, tenant_id, created_at
+	`
+
+	return inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return tx.QueryRowContext(ctx, query, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.ExpiresAt).
+			Scan(&key.ID, &key.TenantID, &key.CreatedAt)
+	})
+}
+
+func (r *apiKeyRepository) List(ctx context.Context) ([]model.APIKey, error) {
+	defer metrics.ObserveQuery("api_keys", "List", time.Now())
+
+	keys := make([]model.APIKey, 0)
+	query := `SELECT * FROM api_keys ORDER BY created_at`
+	err := inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &keys, query)
+	})
+	return keys, err
+}
+
+// Revoke reports whether a key that was not yet revoked was found.
+func (r *apiKeyRepository) Revoke(ctx context.Context, id string) (bool, error) {
+	defer metrics.ObserveQuery("api_keys", "Revoke", time.Now())
+
+	var affected int64
+	query := `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+	err := inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+		affected, err = result.RowsAffected()
+		return err
+	})
+	return affected > 0, err
+}
+
+// GetByHash looks the key up in every tenant; the key's own tenant then
+// binds the request.
+func (r *apiKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	defer metrics.ObserveQuery("api_keys", "GetByHash", time.Now())
+
+	var key model.APIKey
+	query := `SELECT * FROM api_keys WHERE key_hash = $1`
+	err := inTenantTx(tenant.AllTenants(ctx), r.db, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &key, query, hash)
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &key, err
+}
+
+// TouchLastUsed records a use of the key, at most once a minute to spare
+// busy keys a write per request.
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id string) error {
+	defer metrics.ObserveQuery("api_keys", "TouchLastUsed", time.Now())
+
+	query := `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`
+	return inTenantTx(tenant.AllTenants(ctx), r.db, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, id)
+		return err
+	})
+}
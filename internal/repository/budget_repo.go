@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/t5129001t-jpg/subscription-service/internal/metrics"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+)
+
+// BudgetRepository stores users' budgets in the tenant found in ctx.
+type BudgetRepository interface {
+	Upsert(ctx context.Context, budget *model.Budget) error
+	GetByUserID(ctx context.Context, userID string) (*model.Budget, error)
+}
+
+type budgetRepository struct {
+	db *sqlx.DB
+}
+
+func NewBudgetRepository(db *sqlx.DB) BudgetRepository {
+	return &budgetRepository{db: db}
+}
+
+func (r *budgetRepository) Upsert(ctx context.Context, budget *model.Budget) error {
+	defer metrics.ObserveQuery("budgets", "Upsert", time.Now())
+
+	query := `
+		INSERT INTO budgets (user_id, monthly_limit, enforcement)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id, user_id) DO UPDATE
+		SET monthly_limit = EXCLUDED.monthly_limit, enforcement = EXCLUDED.enforcement
+		RETURNING tenant_id, created_at, updated_at
+	`
+
+	return inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return tx.QueryRowContext(ctx, query, budget.UserID, budget.MonthlyLimit, budget.Enforcement).
+			Scan(&budget.TenantID, &budget.CreatedAt, &budget.UpdatedAt)
+	})
+}
+
+func (r *budgetRepository) GetByUserID(ctx context.Context, userID string) (*model.Budget, error) {
+	defer metrics.ObserveQuery("budgets", "GetByUserID", time.Now())
+
+	var budget model.Budget
+	err := inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &budget, `SELECT * FROM budgets WHERE user_id = $1`, userID)
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &budget, err
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+)
+
+const subscriptionChangesChannel = "subscription_changes"
+
+type ChangeListener interface {
+	Changes() <-chan model.SubscriptionChange
+	Close() error
+}
+
+type changeListener struct {
+	listener *pq.Listener
+	changes  chan model.SubscriptionChange
+}
+
+// NewChangeListener listens on the channel notified by the subscriptions
+// table trigger. The connection is re-established automatically; changes
+// made while it was down are not replayed.
+func NewChangeListener(connString string) (ChangeListener, error) {
+	listener := pq.NewListener(connString, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Error("subscription change listener failed", "error", err)
+		}
+	})
+	if err := listener.Listen(subscriptionChangesChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	l := &changeListener{
+		listener: listener,
+		changes:  make(chan model.SubscriptionChange, 64),
+	}
+	go l.run()
+	return l, nil
+}
+
+func (l *changeListener) Changes() <-chan model.SubscriptionChange {
+	return l.changes
+}
+
+func (l *changeListener) Close() error {
+	return l.listener.Close()
+}
+
+func (l *changeListener) run() {
+	defer close(l.changes)
+
+	for notification := range l.listener.Notify {
+		// A nil notification marks a reconnect.
+		if notification == nil {
+			continue
+		}
+
+		var change model.SubscriptionChange
+		if err := json.Unmarshal([]byte(notification.Extra), &change); err != nil {
+			slog.Error("invalid subscription change notification", "error", err)
+			continue
+		}
+		l.changes <- change
+	}
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrConflict         = errors.New("conflicts with an existing record")
+	ErrReferenced       = errors.New("record is still referenced")
+	ErrMissingReference = errors.New("referenced record does not exist")
+)
+
+// violates reports whether err is a Postgres error of the named
+// condition, such as "unique_violation".
+func violates(err error, condition string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Name() == condition
+}
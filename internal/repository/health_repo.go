@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pressly/goose/v3"
+)
+
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (int64, error)
+}
+
+type healthRepository struct {
+	db *sqlx.DB
+}
+
+func NewHealthRepository(db *sqlx.DB) HealthRepository {
+	return &healthRepository{db: db}
+}
+
+func (r *healthRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// MigrationVersion returns the version of the last migration goose applied.
+func (r *healthRepository) MigrationVersion(ctx context.Context) (int64, error) {
+	return goose.GetDBVersionContext(ctx, r.db.DB)
+}
+
+// LatestMigrationVersion returns the version of the last migration in dir,
+// which goose.Up brings the database to.
+func LatestMigrationVersion(dir string) (int64, error) {
+	migrations, err := goose.CollectMigrations(dir, 0, goose.MaxVersion)
+	if err != nil {
+		return 0, err
+	}
+	last, err := migrations.Last()
+	if err != nil {
+		return 0, err
+	}
+	return last.Version, nil
+}
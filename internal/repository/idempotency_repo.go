@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/t5129001t-jpg/subscription-service/internal/metrics"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+)
+
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, record *model.IdempotencyRecord, abandonAfter time.Duration) (bool, error)
+	Get(ctx context.Context, scope, key string) (*model.IdempotencyRecord, error)
+	Complete(ctx context.Context, scope, key string, response *model.StoredResponse) error
+	Delete(ctx context.Context, scope, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepository(db *sqlx.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Reserve claims the record's key for a new request. It returns false when
+// the key is held by a live record: one that has not expired and is not
+// an in-progress request older than abandonAfter, whose handler is
+// presumed gone.
+func (r *idempotencyRepository) Reserve(ctx context.Context, record *model.IdempotencyRecord, abandonAfter time.Duration) (bool, error) {
+	defer metrics.ObserveQuery("idempotency_keys", "Reserve", time.Now())
+
+	query := `
+		INSERT INTO idempotency_keys AS k (scope, key, request_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (scope, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			content_type = NULL,
+			response_body = NULL,
+			created_at = NOW(),
+			expires_at = EXCLUDED.expires_at
+		WHERE k.expires_at < NOW()
+		OR (k.status_code IS NULL AND k.created_at < NOW() - make_interval(secs => $5))
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query, record.Scope, record.Key, record.RequestHash, record.ExpiresAt, abandonAfter.Seconds()).
+		Scan(&record.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	logFailure(ctx, err)
+	return err == nil, err
+}
+
+func (r *idempotencyRepository) Get(ctx context.Context, scope, key string) (*model.IdempotencyRecord, error) {
+	defer metrics.ObserveQuery("idempotency_keys", "Get", time.Now())
+
+	var record model.IdempotencyRecord
+	query := `SELECT * FROM idempotency_keys WHERE scope = $1 AND key = $2`
+	err := r.db.GetContext(ctx, &record, query, scope, key)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	logFailure(ctx, err)
+	return &record, err
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, scope, key string, response *model.StoredResponse) error {
+	defer metrics.ObserveQuery("idempotency_keys", "Complete", time.Now())
+
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $3, content_type = $4, response_body = $5
+		WHERE scope = $1 AND key = $2
+	`
+	_, err := r.db.ExecContext(ctx, query, scope, key, response.StatusCode, response.ContentType, response.Body)
+	logFailure(ctx, err)
+	return err
+}
+
+func (r *idempotencyRepository) Delete(ctx context.Context, scope, key string) error {
+	defer metrics.ObserveQuery("idempotency_keys", "Delete", time.Now())
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2`, scope, key)
+	logFailure(ctx, err)
+	return err
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	defer metrics.ObserveQuery("idempotency_keys", "DeleteExpired", time.Now())
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at < NOW()`)
+	if err != nil {
+		logFailure(ctx, err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+)
+
+type OutboxRepository interface {
+	Relay(ctx context.Context, limit int, publish func(event model.OutboxEvent) error) (int, error)
+}
+
+type outboxRepository struct {
+	db *sqlx.DB
+}
+
+func NewOutboxRepository(db *sqlx.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// Relay hands up to limit unpublished events to publish in insertion order
+// and then marks the ones it accepted as published. Relaying stops at the
+// first failure so that later events of the same aggregate are not
+// published ahead of it; the failed event and everything after it are
+// retried on the next call. No transaction is open while publishing.
+//
+// Only one relay runs at a time, across all instances, under a session
+// advisory lock: a relay that finds it taken returns without publishing.
+// Claiming rows with FOR UPDATE SKIP LOCKED instead would let a second
+// relay skip an aggregate's locked event and publish its next one first,
+// and would hold the row locks, and so a transaction, open for as long as
+// the publisher takes. A single relay keeps the order at the cost of
+// throughput, which one publisher call per event limits anyway.
+func (r *outboxRepository) Relay(ctx context.Context, limit int, publish func(event model.OutboxEvent) error) (int, error) {
+	conn, err := r.db.Connx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.GetContext(ctx, &locked, `SELECT pg_try_advisory_lock(hashtext('outbox_relay'))`); err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+	// The lock belongs to the session, which outlives ctx in the pool.
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext('outbox_relay'))`)
+
+	events := make([]model.OutboxEvent, 0)
+	query := `
+		SELECT * FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+	`
+	if err := conn.SelectContext(ctx, &events, query, limit); err != nil {
+		return 0, err
+	}
+
+	published := make([]int64, 0, len(events))
+	var publishErr error
+	for _, event := range events {
+		if publishErr = publish(event); publishErr != nil {
+			break
+		}
+		published = append(published, event.ID)
+	}
+
+	if len(published) > 0 {
+		query := `UPDATE outbox SET published_at = NOW() WHERE id = ANY($1)`
+		if _, err := conn.ExecContext(context.Background(), query, pq.Array(published)); err != nil {
+			return 0, err
+		}
+	}
+	return len(published), publishErr
+}
+
+// insertSubscriptionEvent records the current state of a subscription in
+// the outbox as part of tx.
+func insertSubscriptionEvent(ctx context.Context, tx *sqlx.Tx, eventType, id string) error {
+	var sub model.Subscription
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions s WHERE s.id = $1`
+	if err := tx.GetContext(ctx, &sub, query, id); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO outbox (aggregate_type, aggregate_id, event_type, payload) VALUES ($1, $2, $3, $4)`,
+		model.AggregateSubscription, id, eventType, string(payload),
+	)
+	return err
+}
+
+func inTx(ctx context.Context, db *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	return inTxWith(ctx, db, nil, fn)
+}
+
+// inTxWith is inTx for a transaction started with opts.
+func inTxWith(ctx context.Context, db *sqlx.DB, opts *sql.TxOptions, fn func(tx *sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/t5129001t-jpg/subscription-service/internal/metrics"
+)
+
+// RateLimitRepository keeps token buckets in Postgres so that every replica
+// counts against the same limits.
+type RateLimitRepository interface {
+	// Take takes a token from the bucket key, refilled at perSecond up to
+	// burst tokens, and returns whether it was allowed and the tokens left.
+	Take(ctx context.Context, key string, burst int, perSecond float64) (bool, float64, error)
+	// Peek returns the tokens in the bucket key without taking one.
+	Peek(ctx context.Context, key string, burst int, perSecond float64) (float64, error)
+	// Prune removes buckets unused for longer than idle.
+	Prune(ctx context.Context, idle time.Duration) (int64, error)
+}
+
+type rateLimitRepository struct {
+	db *sqlx.DB
+}
+
+func NewRateLimitRepository(db *sqlx.DB) RateLimitRepository {
+	return &rateLimitRepository{db: db}
+}
+
+func (r *rateLimitRepository) Take(ctx context.Context, key string, burst int, perSecond float64) (bool, float64, error) {
+	defer metrics.ObserveQuery("rate_limits", "Take", time.Now())
+
+	var allowed bool
+	var tokens float64
+	err := r.db.QueryRowContext(ctx, `SELECT allowed, tokens_left FROM rate_limit_take($1, $2, $3)`, key, burst, perSecond).
+		Scan(&allowed, &tokens)
+	logFailure(ctx, err)
+	return allowed, tokens, err
+}
+
+func (r *rateLimitRepository) Peek(ctx context.Context, key string, burst int, perSecond float64) (float64, error) {
+	defer metrics.ObserveQuery("rate_limits", "Peek", time.Now())
+
+	var tokens float64
+	query := `
+		SELECT COALESCE((
+			SELECT LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * $3)
+			FROM rate_limit_buckets b
+			WHERE b.key = $1
+		), $2::float8)
+	`
+	err := r.db.QueryRowContext(ctx, query, key, burst, perSecond).Scan(&tokens)
+	logFailure(ctx, err)
+	return tokens, err
+}
+
+func (r *rateLimitRepository) Prune(ctx context.Context, idle time.Duration) (int64, error) {
+	defer metrics.ObserveQuery("rate_limits", "Prune", time.Now())
+
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM rate_limit_buckets WHERE updated_at < NOW() - make_interval(secs => $1)`,
+		idle.Seconds(),
+	)
+	if err != nil {
+		logFailure(ctx, err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -1,21 +1,78 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/t5129001t-jpg/subscription-service/internal/metrics"
 	"github.com/t5129001t-jpg/subscription-service/internal/model"
 )
 
+// SubscriptionRepository methods see only the tenant found in ctx.
 type SubscriptionRepository interface {
-	Create(sub *model.Subscription) error
-	GetByID(id string) (*model.Subscription, error)
-	Update(id string, updates map[string]interface{}) error
-	Delete(id string) error
-	List(filter model.SubscriptionFilter) ([]model.Subscription, int, error)
-	GetTotalPrice(userID, serviceName, startMonth, endMonth string) (int, error)
+	Create(ctx context.Context, sub *model.Subscription) error
+	GetByID(ctx context.Context, id string) (*model.Subscription, error)
+	Update(ctx context.Context, id string, updates map[string]interface{}) error
+	Delete(ctx context.Context, id string) error
+	GetDeleted(ctx context.Context, id string) (*model.Subscription, error)
+	Restore(ctx context.Context, id string) error
+	List(ctx context.Context, filter model.SubscriptionFilter) ([]model.Subscription, int, error)
+	GetTotalPrice(ctx context.Context, userID, serviceName, startMonth, endMonth string) (int, error)
+	CreatePause(ctx context.Context, subscriptionID, startMonth string) (*model.SubscriptionPause, error)
+	GetOpenPause(ctx context.Context, subscriptionID string) (*model.SubscriptionPause, error)
+	ClosePause(ctx context.Context, pauseID, endMonth string) error
+	DeletePause(ctx context.Context, pauseID string) error
+	ListPauses(ctx context.Context, subscriptionID string) ([]model.SubscriptionPause, error)
+	ListPausesFor(ctx context.Context, subscriptionIDs []string) (map[string][]model.SubscriptionPause, error)
+	ListByUser(ctx context.Context, userID string) ([]model.Subscription, error)
+	ListBillable(ctx context.Context) ([]model.Subscription, error)
+	FindOverlapping(ctx context.Context, sub *model.Subscription) (*model.Subscription, error)
+	ListOverlaps(ctx context.Context, userID string) ([]model.SubscriptionOverlap, error)
+	ListMembers(ctx context.Context, subscriptionID string) ([]model.SubscriptionMember, error)
+	SetMember(ctx context.Context, member *model.SubscriptionMember) error
+	DeleteMember(ctx context.Context, subscriptionID, userID string) error
+}
+
+// statusExpression derives the effective status from the stored lifecycle
+// status: trials and subscriptions past their end_date move on by calendar,
+// and "paused" comes from a pause interval covering the current month.
+const statusExpression = `
+	CASE
+		WHEN s.status IN ('cancelled', 'expired') THEN s.status
+		WHEN s.end_date IS NOT NULL AND to_date(s.end_date, 'MM-YYYY') < date_trunc('month', NOW()) THEN 'expired'
+		WHEN EXISTS (
+			SELECT 1 FROM subscription_pauses p
+			WHERE p.subscription_id = s.id
+			AND to_date(p.start_month, 'MM-YYYY') <= date_trunc('month', NOW())
+			AND (p.end_month IS NULL OR to_date(p.end_month, 'MM-YYYY') >= date_trunc('month', NOW()))
+		) THEN 'paused'
+		WHEN s.status = 'trial' AND (s.trial_end IS NULL OR to_date(s.trial_end, 'MM-YYYY') < date_trunc('month', NOW())) THEN 'active'
+		ELSE s.status
+	END`
+
+const subscriptionColumns = `
+	s.id, s.tenant_id, s.service_name, s.price, s.user_id, s.start_date, s.end_date, s.trial_end,
+	s.billing_day, s.cancellation_reason, s.cancelled_at, s.created_at, s.updated_at, s.deleted_at,
+	` + statusExpression + ` AS status
+`
+
+// normalizedServiceName compares service names ignoring case and spacing.
+const normalizedServiceName = `lower(regexp_replace(trim(%s), '\s+', ' ', 'g'))`
+
+// notPausedCondition excludes subscriptions that have a pause interval
+// covering the month given by the SQL date expression monthExpr.
+func notPausedCondition(monthExpr string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM subscription_pauses p
+		WHERE p.subscription_id = s.id
+		AND to_date(p.start_month, 'MM-YYYY') <= %[1]s
+		AND (p.end_month IS NULL OR to_date(p.end_month, 'MM-YYYY') >= %[1]s)
+	)`, monthExpr)
 }
 
 type subscriptionRepository struct {
@@ -26,36 +83,53 @@ func NewSubscriptionRepository(db *sqlx.DB) SubscriptionRepository {
 	return &subscriptionRepository{db: db}
 }
 
-func (r *subscriptionRepository) Create(sub *model.Subscription) error {
+func (r *subscriptionRepository) Create(ctx context.Context, sub *model.Subscription) error {
+	defer metrics.ObserveQuery("subscriptions", "Create", time.Now())
+
 	query := `
-		INSERT INTO subscriptions (service_name, price, user_id, start_date, end_date)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO subscriptions (service_name, price, user_id, start_date, end_date, trial_end, status, billing_day)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, created_at, updated_at
 	`
 
-	err := r.db.QueryRow(
-		query,
-		sub.ServiceName,
-		sub.Price,
-		sub.UserID,
-		sub.StartDate,
-		sub.EndDate,
-	).Scan(&sub.ID, &sub.CreatedAt, &sub.UpdatedAt)
-
-	return err
+	return inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			sub.ServiceName,
+			sub.Price,
+			sub.UserID,
+			sub.StartDate,
+			sub.EndDate,
+			sub.TrialEnd,
+			sub.Status,
+			sub.BillingDay,
+		).Scan(&sub.ID, &sub.CreatedAt, &sub.UpdatedAt)
+		if violates(err, "foreign_key_violation") {
+			return ErrMissingReference
+		}
+		if err != nil {
+			return err
+		}
+		return insertSubscriptionEvent(ctx, tx, model.EventSubscriptionCreated, sub.ID)
+	})
 }
 
-func (r *subscriptionRepository) GetByID(id string) (*model.Subscription, error) {
+func (r *subscriptionRepository) GetByID(ctx context.Context, id string) (*model.Subscription, error) {
+	defer metrics.ObserveQuery("subscriptions", "GetByID", time.Now())
+
 	var sub model.Subscription
-	query := `SELECT * FROM subscriptions WHERE id = $1 AND deleted_at IS NULL`
-	err := r.db.Get(&sub, query, id)
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions s WHERE s.id = $1 AND s.deleted_at IS NULL`
+	err := r.get(ctx, &sub, query, id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return &sub, err
 }
 
-func (r *subscriptionRepository) Update(id string, updates map[string]interface{}) error {
+func (r *subscriptionRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	defer metrics.ObserveQuery("subscriptions", "Update", time.Now())
+
 	if len(updates) == 0 {
 		return nil
 	}
@@ -77,54 +151,122 @@ func (r *subscriptionRepository) Update(id string, updates map[string]interface{
 		WHERE id = $%d AND deleted_at IS NULL
 	`, strings.Join(setClauses, ", "), i)
 
-	_, err := r.db.Exec(query, args...)
-	return err
+	return r.execWithEvent(ctx, model.EventSubscriptionUpdated, id, query, args...)
 }
 
-func (r *subscriptionRepository) Delete(id string) error {
+func (r *subscriptionRepository) Delete(ctx context.Context, id string) error {
+	defer metrics.ObserveQuery("subscriptions", "Delete", time.Now())
+
 	query := `UPDATE subscriptions SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
-	_, err := r.db.Exec(query, id)
-	return err
+	return r.execWithEvent(ctx, model.EventSubscriptionDeleted, id, query, id)
+}
+
+// get and selectAll run a single query limited to the tenant in ctx.
+func (r *subscriptionRepository) get(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, dest, query, args...)
+	})
+}
+
+func (r *subscriptionRepository) selectAll(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, dest, query, args...)
+	})
+}
+
+// GetDeleted returns a soft-deleted subscription, or nil when id is not
+// deleted.
+func (r *subscriptionRepository) GetDeleted(ctx context.Context, id string) (*model.Subscription, error) {
+	defer metrics.ObserveQuery("subscriptions", "GetDeleted", time.Now())
+
+	var sub model.Subscription
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions s WHERE s.id = $1 AND s.deleted_at IS NOT NULL`
+	err := r.get(ctx, &sub, query, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &sub, err
+}
+
+func (r *subscriptionRepository) Restore(ctx context.Context, id string) error {
+	defer metrics.ObserveQuery("subscriptions", "Restore", time.Now())
+
+	query := `UPDATE subscriptions SET deleted_at = NULL, updated_at = NOW() WHERE id = $1 AND deleted_at IS NOT NULL`
+	return r.execWithEvent(ctx, model.EventSubscriptionUpdated, id, query, id)
+}
+
+// execWithEvent runs a statement changing subscription id and, when it
+// affected a row, records eventType in the outbox in the same transaction.
+func (r *subscriptionRepository) execWithEvent(ctx context.Context, eventType, id, query string, args ...interface{}) error {
+	return inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if violates(err, "foreign_key_violation") {
+			return ErrMissingReference
+		}
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil || affected == 0 {
+			return err
+		}
+		return insertSubscriptionEvent(ctx, tx, eventType, id)
+	})
 }
 
-func (r *subscriptionRepository) List(filter model.SubscriptionFilter) ([]model.Subscription, int, error) {
+func (r *subscriptionRepository) List(ctx context.Context, filter model.SubscriptionFilter) ([]model.Subscription, int, error) {
+	defer metrics.ObserveQuery("subscriptions", "List", time.Now())
+
 	var subscriptions []model.Subscription
 	var total int
 
-	baseQuery := `FROM subscriptions WHERE deleted_at IS NULL`
+	baseQuery := `FROM subscriptions s`
+	shareColumn := ""
 	args := make([]interface{}, 0)
 	conditions := make([]string, 0)
 	argCount := 1
 
+	// A user sees the subscriptions they own or share, each with their
+	// own portion of the price.
 	if filter.UserID != "" {
-		conditions = append(conditions, fmt.Sprintf("user_id = $%d", argCount))
+		baseQuery += fmt.Sprintf(" JOIN subscription_shares sh ON sh.subscription_id = s.id AND sh.user_id = $%d", argCount)
+		shareColumn = ", sh.amount AS user_share "
 		args = append(args, filter.UserID)
 		argCount++
 	}
+	baseQuery += " WHERE s.deleted_at IS NULL"
 
 	if filter.ServiceName != "" {
-		conditions = append(conditions, fmt.Sprintf("service_name = $%d", argCount))
+		conditions = append(conditions, fmt.Sprintf("s.service_name = $%d", argCount))
 		args = append(args, filter.ServiceName)
 		argCount++
 	}
 
 	if filter.Month != "" {
-		conditions = append(conditions, fmt.Sprintf("(start_date <= $%d AND (end_date IS NULL OR end_date >= $%d))", argCount, argCount))
+		month := fmt.Sprintf("to_date($%d, 'MM-YYYY')", argCount)
+		conditions = append(conditions, fmt.Sprintf(
+			"(to_date(s.start_date, 'MM-YYYY') <= %[1]s AND (s.end_date IS NULL OR to_date(s.end_date, 'MM-YYYY') >= %[1]s))",
+			month,
+		))
+		conditions = append(conditions, notPausedCondition(month))
 		args = append(args, filter.Month)
 		argCount++
 	}
 
+	if filter.Status != "" {
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", statusExpression, argCount))
+		args = append(args, filter.Status)
+		argCount++
+	}
+
 	if len(conditions) > 0 {
 		baseQuery += " AND " + strings.Join(conditions, " AND ")
 	}
 
 	countQuery := "SELECT COUNT(*) " + baseQuery
-	err := r.db.Get(&total, countQuery, args...)
-	if err != nil {
-		return nil, 0, err
-	}
+	countArgs := args
 
-	dataQuery := "SELECT * " + baseQuery + " ORDER BY start_date DESC"
+	dataQuery := "SELECT " + subscriptionColumns + shareColumn + baseQuery + " ORDER BY s.start_date DESC"
 	
 	if filter.Limit > 0 {
 		dataQuery += fmt.Sprintf(" LIMIT $%d", argCount)
@@ -138,23 +280,257 @@ func (r *subscriptionRepository) List(filter model.SubscriptionFilter) ([]model.
 		argCount++
 	}
 
-	err = r.db.Select(&subscriptions, dataQuery, args...)
+	err := inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &total, countQuery, countArgs...); err != nil {
+			return err
+		}
+		return tx.SelectContext(ctx, &subscriptions, dataQuery, args...)
+	})
 	return subscriptions, total, err
 }
 
-func (r *subscriptionRepository) GetTotalPrice(userID, serviceName, startMonth, endMonth string) (int, error) {
+func (r *subscriptionRepository) GetTotalPrice(ctx context.Context, userID, serviceName, startMonth, endMonth string) (int, error) {
+	defer metrics.ObserveQuery("subscriptions", "GetTotalPrice", time.Now())
+
 	var total int
 
 	query := `
-		SELECT COALESCE(SUM(price), 0)
-		FROM subscriptions
-		WHERE deleted_at IS NULL
-		AND ($1 = '' OR $1 IS NULL OR user_id = $1)
-		AND ($2 = '' OR $2 IS NULL OR service_name = $2)
-		AND start_date >= $3
-		AND (end_date IS NULL OR end_date <= $4)
+		SELECT COALESCE(SUM(CASE WHEN $1 = '' THEN s.price ELSE sh.amount END), 0)
+		FROM subscriptions s
+		LEFT JOIN subscription_shares sh
+			ON sh.subscription_id = s.id AND sh.user_id = NULLIF($1, '')::uuid
+		CROSS JOIN generate_series(
+			to_date($3, 'MM-YYYY'), to_date($4, 'MM-YYYY'), INTERVAL '1 month'
+		) AS m(month)
+		WHERE s.deleted_at IS NULL
+		AND ($1 = '' OR sh.user_id IS NOT NULL)
+		AND ($2 = '' OR s.service_name = $2)
+		AND to_date(s.start_date, 'MM-YYYY') <= m.month
+		AND (s.end_date IS NULL OR to_date(s.end_date, 'MM-YYYY') >= m.month)
+		AND (s.trial_end IS NULL OR to_date(s.trial_end, 'MM-YYYY') < m.month)
+		AND ` + notPausedCondition("m.month") + `
 	`
 
-	err := r.db.Get(&total, query, userID, serviceName, startMonth, endMonth)
+	err := r.get(ctx, &total, query, userID, serviceName, startMonth, endMonth)
 	return total, err
 }
+
+// CreatePause opens a pause. A subscription with an open pause already
+// gives ErrConflict.
+func (r *subscriptionRepository) CreatePause(ctx context.Context, subscriptionID, startMonth string) (*model.SubscriptionPause, error) {
+	defer metrics.ObserveQuery("subscriptions", "CreatePause", time.Now())
+
+	pause := &model.SubscriptionPause{
+		SubscriptionID: subscriptionID,
+		StartMonth:     startMonth,
+	}
+
+	query := `
+		INSERT INTO subscription_pauses (subscription_id, start_month)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		err := tx.QueryRowContext(ctx, query, subscriptionID, startMonth).
+			Scan(&pause.ID, &pause.CreatedAt, &pause.UpdatedAt)
+		if violates(err, "unique_violation") {
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+		return insertSubscriptionEvent(ctx, tx, model.EventSubscriptionUpdated, subscriptionID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pause, nil
+}
+
+func (r *subscriptionRepository) GetOpenPause(ctx context.Context, subscriptionID string) (*model.SubscriptionPause, error) {
+	defer metrics.ObserveQuery("subscriptions", "GetOpenPause", time.Now())
+
+	var pause model.SubscriptionPause
+	query := `SELECT * FROM subscription_pauses WHERE subscription_id = $1 AND end_month IS NULL`
+	err := r.get(ctx, &pause, query, subscriptionID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &pause, err
+}
+
+func (r *subscriptionRepository) ClosePause(ctx context.Context, pauseID, endMonth string) error {
+	defer metrics.ObserveQuery("subscriptions", "ClosePause", time.Now())
+
+	query := `UPDATE subscription_pauses SET end_month = $1 WHERE id = $2 RETURNING subscription_id`
+	return r.changePause(ctx, query, endMonth, pauseID)
+}
+
+func (r *subscriptionRepository) DeletePause(ctx context.Context, pauseID string) error {
+	defer metrics.ObserveQuery("subscriptions", "DeletePause", time.Now())
+
+	query := `DELETE FROM subscription_pauses WHERE id = $1 RETURNING subscription_id`
+	return r.changePause(ctx, query, pauseID)
+}
+
+func (r *subscriptionRepository) changePause(ctx context.Context, query string, args ...interface{}) error {
+	return inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var subscriptionID string
+		err := tx.QueryRowContext(ctx, query, args...).Scan(&subscriptionID)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return insertSubscriptionEvent(ctx, tx, model.EventSubscriptionUpdated, subscriptionID)
+	})
+}
+
+func (r *subscriptionRepository) ListPauses(ctx context.Context, subscriptionID string) ([]model.SubscriptionPause, error) {
+	defer metrics.ObserveQuery("subscriptions", "ListPauses", time.Now())
+
+	pauses := make([]model.SubscriptionPause, 0)
+	query := `SELECT * FROM subscription_pauses WHERE subscription_id = $1 ORDER BY to_date(start_month, 'MM-YYYY')`
+	err := r.selectAll(ctx, &pauses, query, subscriptionID)
+	return pauses, err
+}
+
+// ListPausesFor returns the pauses of each of subscriptionIDs, in order,
+// keyed by subscription. Subscriptions without pauses are left out.
+func (r *subscriptionRepository) ListPausesFor(ctx context.Context, subscriptionIDs []string) (map[string][]model.SubscriptionPause, error) {
+	defer metrics.ObserveQuery("subscriptions", "ListPausesFor", time.Now())
+
+	pauses := make([]model.SubscriptionPause, 0)
+	query := `
+		SELECT * FROM subscription_pauses
+		WHERE subscription_id = ANY($1::uuid[])
+		ORDER BY subscription_id, to_date(start_month, 'MM-YYYY')
+	`
+	if err := r.selectAll(ctx, &pauses, query, pq.Array(subscriptionIDs)); err != nil {
+		return nil, err
+	}
+
+	bySubscription := make(map[string][]model.SubscriptionPause)
+	for _, pause := range pauses {
+		bySubscription[pause.SubscriptionID] = append(bySubscription[pause.SubscriptionID], pause)
+	}
+	return bySubscription, nil
+}
+
+func (r *subscriptionRepository) ListByUser(ctx context.Context, userID string) ([]model.Subscription, error) {
+	defer metrics.ObserveQuery("subscriptions", "ListByUser", time.Now())
+
+	subscriptions := make([]model.Subscription, 0)
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions s WHERE s.user_id = $1 AND s.deleted_at IS NULL`
+	err := r.selectAll(ctx, &subscriptions, query, userID)
+	return subscriptions, err
+}
+
+func (r *subscriptionRepository) ListBillable(ctx context.Context) ([]model.Subscription, error) {
+	defer metrics.ObserveQuery("subscriptions", "ListBillable", time.Now())
+
+	subscriptions := make([]model.Subscription, 0)
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM subscriptions s
+		WHERE s.deleted_at IS NULL
+		AND (s.end_date IS NULL OR to_date(s.end_date, 'MM-YYYY') >= date_trunc('month', NOW()))
+	`
+	err := r.selectAll(ctx, &subscriptions, query)
+	return subscriptions, err
+}
+
+// FindOverlapping returns another live subscription of the same user to the
+// same service whose period overlaps sub's, if there is one.
+func (r *subscriptionRepository) FindOverlapping(ctx context.Context, sub *model.Subscription) (*model.Subscription, error) {
+	defer metrics.ObserveQuery("subscriptions", "FindOverlapping", time.Now())
+
+	var conflicting model.Subscription
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM subscriptions s
+		WHERE s.deleted_at IS NULL
+		AND s.user_id = $1
+		AND ` + fmt.Sprintf(normalizedServiceName, "s.service_name") + ` = ` + fmt.Sprintf(normalizedServiceName, "$2") + `
+		AND ($3 = '' OR s.id <> NULLIF($3, '')::uuid)
+		AND ($5::text IS NULL OR to_date(s.start_date, 'MM-YYYY') <= to_date($5, 'MM-YYYY'))
+		AND (s.end_date IS NULL OR to_date(s.end_date, 'MM-YYYY') >= to_date($4, 'MM-YYYY'))
+		ORDER BY to_date(s.start_date, 'MM-YYYY')
+		LIMIT 1
+	`
+
+	err := r.get(ctx, &conflicting, query, sub.UserID, sub.ServiceName, sub.ID, sub.StartDate, sub.EndDate)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &conflicting, err
+}
+
+// ListOverlaps returns every pair of overlapping subscriptions, optionally
+// for one user only.
+func (r *subscriptionRepository) ListOverlaps(ctx context.Context, userID string) ([]model.SubscriptionOverlap, error) {
+	defer metrics.ObserveQuery("subscriptions", "ListOverlaps", time.Now())
+
+	overlaps := make([]model.SubscriptionOverlap, 0)
+	query := `
+		SELECT
+			a.user_id,
+			a.service_name,
+			a.id AS first_id,
+			b.id AS second_id,
+			to_char(GREATEST(to_date(a.start_date, 'MM-YYYY'), to_date(b.start_date, 'MM-YYYY')), 'MM-YYYY') AS overlap_start,
+			to_char(LEAST(to_date(a.end_date, 'MM-YYYY'), to_date(b.end_date, 'MM-YYYY')), 'MM-YYYY') AS overlap_end
+		FROM subscriptions a
+		JOIN subscriptions b
+			ON b.user_id = a.user_id
+			AND ` + fmt.Sprintf(normalizedServiceName, "b.service_name") + ` = ` + fmt.Sprintf(normalizedServiceName, "a.service_name") + `
+			AND a.id < b.id
+		WHERE a.deleted_at IS NULL AND b.deleted_at IS NULL
+		AND ($1 = '' OR a.user_id = NULLIF($1, '')::uuid)
+		AND (b.end_date IS NULL OR to_date(b.end_date, 'MM-YYYY') >= to_date(a.start_date, 'MM-YYYY'))
+		AND (a.end_date IS NULL OR to_date(a.end_date, 'MM-YYYY') >= to_date(b.start_date, 'MM-YYYY'))
+		ORDER BY a.user_id, a.service_name, a.id
+	`
+	err := r.selectAll(ctx, &overlaps, query, userID)
+	return overlaps, err
+}
+
+func (r *subscriptionRepository) ListMembers(ctx context.Context, subscriptionID string) ([]model.SubscriptionMember, error) {
+	defer metrics.ObserveQuery("subscriptions", "ListMembers", time.Now())
+
+	members := make([]model.SubscriptionMember, 0)
+	query := `SELECT * FROM subscription_members WHERE subscription_id = $1 ORDER BY created_at`
+	err := r.selectAll(ctx, &members, query, subscriptionID)
+	return members, err
+}
+
+// SetMember adds member to the subscription or replaces their share.
+func (r *subscriptionRepository) SetMember(ctx context.Context, member *model.SubscriptionMember) error {
+	defer metrics.ObserveQuery("subscriptions", "SetMember", time.Now())
+
+	query := `
+		INSERT INTO subscription_members (subscription_id, user_id, share_type, share_value)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (subscription_id, user_id)
+		DO UPDATE SET share_type = EXCLUDED.share_type, share_value = EXCLUDED.share_value, updated_at = NOW()
+		RETURNING created_at, updated_at
+	`
+
+	return inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		err := tx.QueryRowContext(ctx, query, member.SubscriptionID, member.UserID, member.ShareType, member.ShareValue).
+			Scan(&member.CreatedAt, &member.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return insertSubscriptionEvent(ctx, tx, model.EventSubscriptionUpdated, member.SubscriptionID)
+	})
+}
+
+func (r *subscriptionRepository) DeleteMember(ctx context.Context, subscriptionID, userID string) error {
+	defer metrics.ObserveQuery("subscriptions", "DeleteMember", time.Now())
+
+	query := `DELETE FROM subscription_members WHERE subscription_id = $1 AND user_id = $2`
+	return r.execWithEvent(ctx, model.EventSubscriptionUpdated, subscriptionID, query, subscriptionID, userID)
+}
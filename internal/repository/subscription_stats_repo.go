@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/t5129001t-jpg/subscription-service/internal/metrics"
+)
+
+type SubscriptionStatsRepository interface {
+	CountByStatus(ctx context.Context) (map[string]map[string]int, error)
+}
+
+type subscriptionStatsRepository struct {
+	db *sqlx.DB
+}
+
+func NewSubscriptionStatsRepository(db *sqlx.DB) SubscriptionStatsRepository {
+	return &subscriptionStatsRepository{db: db}
+}
+
+// CountByStatus counts the live subscriptions of every tenant by tenant and
+// effective status.
+func (r *subscriptionStatsRepository) CountByStatus(ctx context.Context) (map[string]map[string]int, error) {
+	defer metrics.ObserveQuery("subscription_stats", "CountByStatus", time.Now())
+
+	var rows []struct {
+		TenantID string `db:"tenant_id"`
+		Status   string `db:"status"`
+		Count    int    `db:"count"`
+	}
+	query := `
+		SELECT tenant_id, status, COUNT(*) AS count
+		FROM (
+			SELECT s.tenant_id, ` + statusExpression + ` AS status
+			FROM subscriptions s
+			WHERE s.deleted_at IS NULL
+		) effective
+		GROUP BY tenant_id, status
+	`
+
+	err := inTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		if err := allTenants(ctx, tx); err != nil {
+			return err
+		}
+		return tx.SelectContext(ctx, &rows, query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]map[string]int)
+	for _, row := range rows {
+		if counts[row.TenantID] == nil {
+			counts[row.TenantID] = make(map[string]int)
+		}
+		counts[row.TenantID][row.Status] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/t5129001t-jpg/subscription-service/internal/logging"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
+)
+
+// ErrNoTenant is returned for tenant data requested without a tenant in
+// the context. Row-level security would return nothing anyway.
+var ErrNoTenant = errors.New("no tenant in context")
+
+// inTenantTx runs fn in a transaction that row-level security limits to the
+// tenant in ctx, or opens to every tenant when ctx is marked with
+// tenant.AllTenants. Unexpected errors are logged with the request's logger.
+func inTenantTx(ctx context.Context, db *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	return inTenantTxWith(ctx, db, nil, fn)
+}
+
+// inTenantTxWith is inTenantTx for a transaction started with opts.
+func inTenantTxWith(ctx context.Context, db *sqlx.DB, opts *sql.TxOptions, fn func(tx *sqlx.Tx) error) error {
+	err := inTxWith(ctx, db, opts, func(tx *sqlx.Tx) error {
+		if err := setTenant(ctx, tx); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+	logFailure(ctx, err)
+	return err
+}
+
+// logFailure logs err with the request's logger unless it is expected.
+func logFailure(ctx context.Context, err error) {
+	if err != nil && !isExpected(err) {
+		logging.FromContext(ctx).Error("database query failed", "error", err)
+	}
+}
+
+// isExpected reports whether err is an outcome callers handle, rather than
+// a failure.
+func isExpected(err error) bool {
+	return errors.Is(err, sql.ErrNoRows) || errors.Is(err, ErrConflict) ||
+		errors.Is(err, ErrReferenced) || errors.Is(err, ErrMissingReference) ||
+		errors.Is(err, errNothingErased)
+}
+
+// setTenant is SET LOCAL for tx, through set_config so that the tenant can
+// be passed as a parameter.
+func setTenant(ctx context.Context, tx *sqlx.Tx) error {
+	if tenant.IsAllTenants(ctx) {
+		return allTenants(ctx, tx)
+	}
+
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+	_, err := tx.ExecContext(ctx, `SELECT set_config('app.tenant_id', $1, true)`, id)
+	return err
+}
+
+// allTenants lifts the tenant limit for tx, for data such as a user's that
+// spans tenants.
+func allTenants(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `SELECT set_config('app.all_tenants', 'on', true)`)
+	return err
+}
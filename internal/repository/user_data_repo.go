@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/t5129001t-jpg/subscription-service/internal/metrics"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+)
+
+// UserDataRepository exports and erases all data held about a user in the
+// tenant found in ctx, or in every tenant when ctx is marked with
+// tenant.AllTenants.
+type UserDataRepository interface {
+	Export(ctx context.Context, userID string) (*model.UserExport, error)
+	Erase(ctx context.Context, userID, mode string) (*model.ErasureReceipt, error)
+}
+
+// errNothingErased rolls back an erasure that found no data, so that no
+// receipt is issued for it.
+var errNothingErased = errors.New("nothing to erase")
+
+type userDataRepository struct {
+	db      *sqlx.DB
+	hashKey []byte
+}
+
+// NewUserDataRepository identifies erased users in receipts by the
+// HMAC-SHA256 of their id under hashKey, so that a receipt cannot be
+// matched to a user without the key.
+func NewUserDataRepository(db *sqlx.DB, hashKey []byte) UserDataRepository {
+	return &userDataRepository{db: db, hashKey: hashKey}
+}
+
+// Export reads the user's data from a single snapshot. It returns nil when
+// there is neither a user nor any subscription with that id.
+func (r *userDataRepository) Export(ctx context.Context, userID string) (*model.UserExport, error) {
+	defer metrics.ObserveQuery("user_data", "Export", time.Now())
+
+	var export *model.UserExport
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	err := inTenantTxWith(ctx, r.db, opts, func(tx *sqlx.Tx) error {
+		var err error
+		export, err = r.export(ctx, tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (r *userDataRepository) export(ctx context.Context, tx *sqlx.Tx, userID string) (*model.UserExport, error) {
+	export := &model.UserExport{
+		ExportedAt:    time.Now().UTC(),
+		Subscriptions: make([]model.ExportedSubscription, 0),
+		Memberships:   make([]model.SubscriptionMember, 0),
+		Events:        make([]model.OutboxEvent, 0),
+	}
+
+	var user model.User
+	err := tx.GetContext(ctx, &user, `SELECT * FROM users WHERE id = $1`, userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if err == nil {
+		export.User = &user
+	}
+
+	subscriptions := make([]model.Subscription, 0)
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions s WHERE s.user_id = $1 ORDER BY s.created_at`
+	if err := tx.SelectContext(ctx, &subscriptions, query, userID); err != nil {
+		return nil, err
+	}
+	if export.User == nil && len(subscriptions) == 0 {
+		return nil, nil
+	}
+
+	pauses := make([]model.SubscriptionPause, 0)
+	query = `
+		SELECT p.* FROM subscription_pauses p
+		JOIN subscriptions s ON s.id = p.subscription_id
+		WHERE s.user_id = $1
+		ORDER BY to_date(p.start_month, 'MM-YYYY')
+	`
+	if err := tx.SelectContext(ctx, &pauses, query, userID); err != nil {
+		return nil, err
+	}
+
+	members := make([]model.SubscriptionMember, 0)
+	query = `
+		SELECT m.* FROM subscription_members m
+		JOIN subscriptions s ON s.id = m.subscription_id
+		WHERE s.user_id = $1
+		ORDER BY m.created_at
+	`
+	if err := tx.SelectContext(ctx, &members, query, userID); err != nil {
+		return nil, err
+	}
+
+	for _, sub := range subscriptions {
+		exported := model.ExportedSubscription{
+			Subscription: sub,
+			DeletedAt:    sub.DeletedAt,
+			Pauses:       make([]model.SubscriptionPause, 0),
+			Members:      make([]model.SubscriptionMember, 0),
+		}
+		for _, pause := range pauses {
+			if pause.SubscriptionID == sub.ID {
+				exported.Pauses = append(exported.Pauses, pause)
+			}
+		}
+		for _, member := range members {
+			if member.SubscriptionID == sub.ID {
+				exported.Members = append(exported.Members, member)
+			}
+		}
+		export.Subscriptions = append(export.Subscriptions, exported)
+	}
+
+	query = `SELECT * FROM subscription_members WHERE user_id = $1 ORDER BY created_at`
+	if err := tx.SelectContext(ctx, &export.Memberships, query, userID); err != nil {
+		return nil, err
+	}
+
+	var budget model.Budget
+	err = tx.GetContext(ctx, &budget, `SELECT * FROM budgets WHERE user_id = $1`, userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if err == nil {
+		export.Budget = &budget
+	}
+
+	query = `
+		SELECT o.* FROM outbox o
+		JOIN subscriptions s ON s.id = o.aggregate_id
+		WHERE o.aggregate_type = $2 AND s.user_id = $1
+		ORDER BY o.id
+	`
+	if err := tx.SelectContext(ctx, &export.Events, query, userID, model.AggregateSubscription); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// Erase removes the user's data in one transaction and stores a receipt.
+// In delete mode every row is removed. In anonymise mode subscriptions and
+// shares are kept for reporting but moved to a new, nameless user, and
+// free-text cancellation reasons are cleared. Event history and webhook
+// payloads naming the user are removed in both modes; in delete mode a
+// subscription.deleted event is then recorded for each live subscription
+// removed. Erase returns nil when there was nothing to erase.
+func (r *userDataRepository) Erase(ctx context.Context, userID, mode string) (*model.ErasureReceipt, error) {
+	defer metrics.ObserveQuery("user_data", "Erase", time.Now())
+
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(userID))
+	receipt := &model.ErasureReceipt{
+		SubjectHash: hex.EncodeToString(mac.Sum(nil)),
+		Mode:        mode,
+		Counts:      make(map[string]int64),
+	}
+
+	err := inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var total int64
+		exec := func(kind, query string, args ...interface{}) error {
+			result, err := tx.ExecContext(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			receipt.Counts[kind] += affected
+			total += affected
+			return nil
+		}
+
+		steps := []struct {
+			kind  string
+			query string
+		}{
+			{"events", `
+				DELETE FROM outbox
+				WHERE aggregate_type = 'subscription'
+				AND aggregate_id IN (SELECT id FROM subscriptions WHERE user_id = $1)
+			`},
+			{"webhook_deliveries", `DELETE FROM webhook_deliveries WHERE payload -> 'data' ->> 'user_id' = $1::text`},
+			{"budgets", `DELETE FROM budgets WHERE user_id = $1`},
+		}
+		for _, step := range steps {
+			if err := exec(step.kind, step.query, userID); err != nil {
+				return err
+			}
+		}
+
+		if mode == model.ErasureModeAnonymise {
+			// Subscriptions must stay owned by a user of their own tenant,
+			// so each tenant holding the user's rows gets its own stand-in.
+			var anonymous []struct {
+				ID       string `db:"id"`
+				TenantID string `db:"tenant_id"`
+			}
+			query := `
+				INSERT INTO users (tenant_id)
+				SELECT s.tenant_id FROM subscriptions s
+				WHERE s.user_id = $1
+				OR EXISTS (SELECT 1 FROM subscription_members m WHERE m.subscription_id = s.id AND m.user_id = $1)
+				GROUP BY s.tenant_id
+				RETURNING id, tenant_id
+			`
+			if err := tx.SelectContext(ctx, &anonymous, query, userID); err != nil {
+				return err
+			}
+			for _, stand := range anonymous {
+				query = `
+					UPDATE subscription_members m SET user_id = $2
+					FROM subscriptions s
+					WHERE s.id = m.subscription_id AND s.tenant_id = $3 AND m.user_id = $1
+				`
+				if err := exec("memberships", query, userID, stand.ID, stand.TenantID); err != nil {
+					return err
+				}
+				query = `
+					UPDATE subscriptions SET user_id = $2, cancellation_reason = NULL
+					WHERE user_id = $1 AND tenant_id = $3
+				`
+				if err := exec("subscriptions", query, userID, stand.ID, stand.TenantID); err != nil {
+					return err
+				}
+			}
+		} else {
+			if err := exec("memberships", `DELETE FROM subscription_members WHERE user_id = $1`, userID); err != nil {
+				return err
+			}
+			var live []string
+			query := `SELECT id FROM subscriptions WHERE user_id = $1 AND deleted_at IS NULL`
+			if err := tx.SelectContext(ctx, &live, query, userID); err != nil {
+				return err
+			}
+			for _, id := range live {
+				if err := insertSubscriptionEvent(ctx, tx, model.EventSubscriptionDeleted, id); err != nil {
+					return err
+				}
+			}
+			// Pauses and members of the user's subscriptions go with them.
+			if err := exec("subscriptions", `DELETE FROM subscriptions WHERE user_id = $1`, userID); err != nil {
+				return err
+			}
+		}
+
+		if err := exec("users", `DELETE FROM users WHERE id = $1`, userID); err != nil {
+			return err
+		}
+		if total == 0 {
+			return errNothingErased
+		}
+
+		counts, err := json.Marshal(receipt.Counts)
+		if err != nil {
+			return err
+		}
+		query := `INSERT INTO erasure_receipts (subject_hash, mode, counts) VALUES ($1, $2, $3) RETURNING id, erased_at`
+		return tx.QueryRowContext(ctx, query, receipt.SubjectHash, receipt.Mode, string(counts)).Scan(&receipt.ID, &receipt.ErasedAt)
+	})
+	if err == errNothingErased {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/t5129001t-jpg/subscription-service/internal/metrics"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+)
+
+type UserRepository interface {
+	Create(ctx context.Context, user *model.User) error
+	GetByID(ctx context.Context, id string) (*model.User, error)
+	Update(ctx context.Context, id string, updates map[string]interface{}) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, limit, offset int) ([]model.User, int, error)
+	GetSummary(ctx context.Context, id string) (*model.UserSummary, error)
+}
+
+type userRepository struct {
+	db *sqlx.DB
+}
+
+func NewUserRepository(db *sqlx.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+// Create inserts user into the tenant in ctx, generating an ID unless one is
+// set. A taken ID or email gives ErrConflict.
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	defer metrics.ObserveQuery("users", "Create", time.Now())
+
+	query := `
+		INSERT INTO users (id, name, email)
+		VALUES (COALESCE(NULLIF($1, '')::uuid, uuid_generate_v4()), $2, $3)
+		RETURNING id, tenant_id, created_at, updated_at
+	`
+
+	return inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		err := tx.QueryRowContext(ctx, query, user.ID, user.Name, user.Email).
+			Scan(&user.ID, &user.TenantID, &user.CreatedAt, &user.UpdatedAt)
+		if violates(err, "unique_violation") {
+			return ErrConflict
+		}
+		return err
+	})
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
+	defer metrics.ObserveQuery("users", "GetByID", time.Now())
+
+	var user model.User
+	err := inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &user, `SELECT * FROM users WHERE id = $1`, id)
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &user, err
+}
+
+func (r *userRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	defer metrics.ObserveQuery("users", "Update", time.Now())
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	setClauses := make([]string, 0, len(updates))
+	args := make([]interface{}, 0, len(updates)+1)
+	i := 1
+
+	for field, value := range updates {
+		setClauses = append(setClauses, fmt.Sprintf("%s = $%d", field, i))
+		args = append(args, value)
+		i++
+	}
+
+	args = append(args, id)
+	query := fmt.Sprintf(`UPDATE users SET %s WHERE id = $%d`, strings.Join(setClauses, ", "), i)
+
+	return inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, args...)
+		if violates(err, "unique_violation") {
+			return ErrConflict
+		}
+		return err
+	})
+}
+
+// Delete removes the user from the tenant in ctx. A user still owning
+// subscriptions there, deleted ones included, gives ErrReferenced.
+func (r *userRepository) Delete(ctx context.Context, id string) error {
+	defer metrics.ObserveQuery("users", "Delete", time.Now())
+
+	return inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+		if violates(err, "foreign_key_violation") {
+			return ErrReferenced
+		}
+		return err
+	})
+}
+
+func (r *userRepository) List(ctx context.Context, limit, offset int) ([]model.User, int, error) {
+	defer metrics.ObserveQuery("users", "List", time.Now())
+
+	var total int
+	users := make([]model.User, 0)
+	err := inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &total, `SELECT COUNT(*) FROM users`); err != nil {
+			return err
+		}
+		query := `SELECT * FROM users ORDER BY created_at, id LIMIT $1 OFFSET $2`
+		return tx.SelectContext(ctx, &users, query, limit, offset)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// GetSummary computes the user's summary in a single query: the user's
+// share of every live subscription of the tenant in ctx, expanded into the
+// months billed so far.
+func (r *userRepository) GetSummary(ctx context.Context, id string) (*model.UserSummary, error) {
+	defer metrics.ObserveQuery("users", "GetSummary", time.Now())
+
+	var summary model.UserSummary
+	query := `
+		WITH user_subscriptions AS (
+			SELECT s.*, sh.amount, ` + statusExpression + ` AS effective_status
+			FROM subscription_shares sh
+			JOIN subscriptions s ON s.id = sh.subscription_id
+			WHERE sh.user_id = $1 AND s.deleted_at IS NULL
+		),
+		billed AS (
+			SELECT s.amount, m.month
+			FROM user_subscriptions s
+			CROSS JOIN generate_series(
+				to_date(s.start_date, 'MM-YYYY'),
+				LEAST(COALESCE(to_date(s.end_date, 'MM-YYYY'), CURRENT_DATE), date_trunc('month', NOW())::date),
+				INTERVAL '1 month'
+			) AS m(month)
+			WHERE (s.trial_end IS NULL OR to_date(s.trial_end, 'MM-YYYY') < m.month)
+			AND ` + notPausedCondition("m.month") + `
+		)
+		SELECT
+			u.id AS user_id,
+			(SELECT COUNT(*) FROM user_subscriptions WHERE effective_status = 'active') AS active_subscriptions,
+			(SELECT COALESCE(SUM(amount), 0) FROM billed WHERE month = date_trunc('month', NOW())) AS monthly_spend,
+			(SELECT COALESCE(SUM(amount), 0) FROM billed) AS lifetime_spend,
+			(
+				SELECT service_name FROM user_subscriptions
+				WHERE effective_status = 'active'
+				ORDER BY amount DESC, service_name
+				LIMIT 1
+			) AS most_expensive_service
+		FROM users u
+		WHERE u.id = $1
+	`
+
+	err := inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &summary, query, id)
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &summary, err
+}
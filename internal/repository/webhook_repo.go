@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/t5129001t-jpg/subscription-service/internal/metrics"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+)
+
+// WebhookRepository methods see only the webhooks of the tenant in ctx,
+// and the deliveries of those webhooks.
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *model.Webhook) error
+	GetByID(ctx context.Context, id string) (*model.Webhook, error)
+	List(ctx context.Context) ([]model.Webhook, error)
+	Delete(ctx context.Context, id string) error
+	ListByEventType(ctx context.Context, tenantID, eventType string) ([]model.Webhook, error)
+	EnqueueDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]model.WebhookDelivery, error)
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]model.WebhookDelivery, error)
+	HoldsLease(ctx context.Context, id string, leaseToken *string) (bool, error)
+	MarkDelivered(ctx context.Context, id string, leaseToken *string, attempts int) error
+	MarkRetry(ctx context.Context, id string, leaseToken *string, attempts int, nextAttemptAt time.Time, lastError string) error
+	MarkFailed(ctx context.Context, id string, leaseToken *string, attempts int, lastError string) error
+}
+
+type webhookRepository struct {
+	db *sqlx.DB
+}
+
+func NewWebhookRepository(db *sqlx.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+// Create registers webhook for the tenant in ctx.
+func (r *webhookRepository) Create(ctx context.Context, webhook *model.Webhook) error {
+	defer metrics.ObserveQuery("webhooks", "Create", time.Now())
+
+	query := `
+		INSERT INTO webhooks (url, secret, event_types)
+		VALUES ($1, $2, $3)
+		RETURNING id, tenant_id, active, created_at, updated_at
+	`
+
+	return inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return tx.QueryRowContext(ctx, query, webhook.URL, webhook.Secret, webhook.EventTypes).
+			Scan(&webhook.ID, &webhook.TenantID, &webhook.Active, &webhook.CreatedAt, &webhook.UpdatedAt)
+	})
+}
+
+func (r *webhookRepository) GetByID(ctx context.Context, id string) (*model.Webhook, error) {
+	defer metrics.ObserveQuery("webhooks", "GetByID", time.Now())
+
+	var webhook model.Webhook
+	query := `SELECT * FROM webhooks WHERE id = $1 AND deleted_at IS NULL`
+	err := inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &webhook, query, id)
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &webhook, err
+}
+
+func (r *webhookRepository) List(ctx context.Context) ([]model.Webhook, error) {
+	defer metrics.ObserveQuery("webhooks", "List", time.Now())
+
+	webhooks := make([]model.Webhook, 0)
+	query := `SELECT * FROM webhooks WHERE deleted_at IS NULL ORDER BY created_at`
+	err := inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &webhooks, query)
+	})
+	return webhooks, err
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, id string) error {
+	defer metrics.ObserveQuery("webhooks", "Delete", time.Now())
+
+	query := `UPDATE webhooks SET deleted_at = NOW(), active = FALSE WHERE id = $1 AND deleted_at IS NULL`
+	return r.exec(ctx, query, id)
+}
+
+// ListByEventType returns tenantID's active webhooks subscribed to
+// eventType. The tenant is matched explicitly as well, as the dispatcher
+// queues renewals from a context open to every tenant.
+func (r *webhookRepository) ListByEventType(ctx context.Context, tenantID, eventType string) ([]model.Webhook, error) {
+	defer metrics.ObserveQuery("webhooks", "ListByEventType", time.Now())
+
+	webhooks := make([]model.Webhook, 0)
+	query := `
+		SELECT * FROM webhooks
+		WHERE deleted_at IS NULL AND active AND tenant_id = $1 AND $2 = ANY(event_types)
+	`
+	err := inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &webhooks, query, tenantID, eventType)
+	})
+	return webhooks, err
+}
+
+func (r *webhookRepository) EnqueueDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	defer metrics.ObserveQuery("webhooks", "EnqueueDelivery", time.Now())
+
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_type, payload, dedupe_key)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (dedupe_key) WHERE dedupe_key IS NOT NULL DO NOTHING
+	`
+
+	return r.exec(ctx, query, delivery.WebhookID, delivery.EventType, string(delivery.Payload), delivery.DedupeKey)
+}
+
+func (r *webhookRepository) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]model.WebhookDelivery, error) {
+	defer metrics.ObserveQuery("webhooks", "ListDeliveries", time.Now())
+
+	deliveries := make([]model.WebhookDelivery, 0)
+	query := `
+		SELECT d.*, '' AS url, '' AS secret
+		FROM webhook_deliveries d
+		WHERE d.webhook_id = $1
+		ORDER BY d.created_at DESC
+		LIMIT $2
+	`
+	err := inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &deliveries, query, webhookID, limit)
+	})
+	return deliveries, err
+}
+
+// ClaimDueDeliveries picks pending deliveries whose next attempt is due and
+// pushes next_attempt_at forward by lease, so that other dispatchers skip
+// them while they are in flight. A delivery whose dispatcher dies is picked
+// up again once the lease runs out, under a new lease token. Deliveries of
+// every tenant are claimed when ctx is marked with tenant.AllTenants.
+func (r *webhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]model.WebhookDelivery, error) {
+	defer metrics.ObserveQuery("webhooks", "ClaimDueDeliveries", time.Now())
+
+	deliveries := make([]model.WebhookDelivery, 0)
+	query := `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 second', lease_token = uuid_generate_v4()
+		FROM due, webhooks w
+		WHERE d.id = due.id AND w.id = d.webhook_id
+		RETURNING d.*, w.url, w.secret
+	`
+	err := inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &deliveries, query, limit, lease.Seconds())
+	})
+	return deliveries, err
+}
+
+// HoldsLease reports whether the delivery is still pending under
+// leaseToken, that is, whether no other dispatcher has claimed it since.
+func (r *webhookRepository) HoldsLease(ctx context.Context, id string, leaseToken *string) (bool, error) {
+	defer metrics.ObserveQuery("webhooks", "HoldsLease", time.Now())
+
+	var held bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM webhook_deliveries
+			WHERE id = $1 AND lease_token = $2 AND status = 'pending'
+		)
+	`
+	err := inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &held, query, id, leaseToken)
+	})
+	return held, err
+}
+
+// MarkDelivered, MarkRetry and MarkFailed record the outcome of an attempt
+// and release the lease. They do nothing once leaseToken has lost the
+// delivery to another dispatcher.
+func (r *webhookRepository) MarkDelivered(ctx context.Context, id string, leaseToken *string, attempts int) error {
+	defer metrics.ObserveQuery("webhooks", "MarkDelivered", time.Now())
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = $1, delivered_at = NOW(), last_error = NULL, lease_token = NULL
+		WHERE id = $2 AND lease_token = $3
+	`
+	return r.exec(ctx, query, attempts, id, leaseToken)
+}
+
+func (r *webhookRepository) MarkRetry(ctx context.Context, id string, leaseToken *string, attempts int, nextAttemptAt time.Time, lastError string) error {
+	defer metrics.ObserveQuery("webhooks", "MarkRetry", time.Now())
+
+	query := `
+		UPDATE webhook_deliveries
+		SET attempts = $1, next_attempt_at = $2, last_error = $3, lease_token = NULL
+		WHERE id = $4 AND lease_token = $5
+	`
+	return r.exec(ctx, query, attempts, nextAttemptAt, lastError, id, leaseToken)
+}
+
+func (r *webhookRepository) MarkFailed(ctx context.Context, id string, leaseToken *string, attempts int, lastError string) error {
+	defer metrics.ObserveQuery("webhooks", "MarkFailed", time.Now())
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'failed', attempts = $1, last_error = $2, lease_token = NULL
+		WHERE id = $3 AND lease_token = $4
+	`
+	return r.exec(ctx, query, attempts, lastError, id, leaseToken)
+}
+
+func (r *webhookRepository) exec(ctx context.Context, query string, args ...interface{}) error {
+	return inTenantTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+
+	"github.com/t5129001t-jpg/subscription-service/internal/authz"
+	"github.com/t5129001t-jpg/subscription-service/internal/handler"
+	"github.com/t5129001t-jpg/subscription-service/internal/middleware"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/tracing"
+)
+
+// NewRouter registers every route of the service. Any handler may be nil
+// when the router is only built to list its routes.
+func NewRouter(
+	subscriptionHandler *handler.SubscriptionHandler,
+	webhookHandler *handler.WebhookHandler,
+	streamHandler *handler.StreamHandler,
+	budgetHandler *handler.BudgetHandler,
+	userHandler *handler.UserHandler,
+	apiKeyHandler *handler.APIKeyHandler,
+	healthHandler *handler.HealthHandler,
+	docsHandler *handler.DocsHandler,
+	authenticate gin.HandlerFunc,
+	authorizer authz.Authorizer,
+	resolveTenant gin.HandlerFunc,
+	rateLimiter *middleware.RateLimiter,
+	validate gin.HandlerFunc,
+	idempotency gin.HandlerFunc,
+	logger *slog.Logger,
+) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+
+	r := gin.New()
+
+	r.Use(otelgin.Middleware(tracing.ServiceName, otelgin.WithFilter(func(req *http.Request) bool {
+		switch req.URL.Path {
+		case "/metrics", "/healthz", "/readyz":
+			return false
+		}
+		return true
+	})))
+	r.Use(middleware.RequestLogger(logger))
+	r.Use(middleware.Metrics())
+	// Innermost, so that the logger and the metrics see panics as 500s.
+	r.Use(middleware.Recover())
+
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	r.GET("/healthz", healthHandler.Healthz)
+	r.GET("/readyz", healthHandler.Readyz)
+	r.GET("/openapi.json", docsHandler.OpenAPI)
+	r.GET("/swagger/index.html", docsHandler.SwaggerUI)
+
+	api := r.Group("/api/v1")
+	if authenticate != nil {
+		api.Use(rateLimiter.LimitFailedAuth("auth"), authenticate)
+	}
+	api.Use(resolveTenant)
+	{
+		subscriptions := api.Group("/subscriptions",
+			rateLimiter.Limit("subscriptions"),
+			middleware.RequireScopes(model.ScopeSubscriptionsRead, model.ScopeSubscriptionsWrite),
+			validate)
+		{
+			subscriptions.POST("/", idempotency, subscriptionHandler.CreateSubscription)
+			subscriptions.GET("/", subscriptionHandler.ListSubscriptions)
+			subscriptions.GET("/total", subscriptionHandler.GetTotalPrice)
+			subscriptions.GET("/stream", streamHandler.StreamSubscriptions)
+			subscriptions.GET("/overlaps", subscriptionHandler.ListOverlaps)
+			subscriptions.GET("/:id", subscriptionHandler.GetSubscription)
+			subscriptions.PUT("/:id", subscriptionHandler.UpdateSubscription)
+			subscriptions.DELETE("/:id", subscriptionHandler.DeleteSubscription)
+			subscriptions.POST("/:id/restore", subscriptionHandler.RestoreSubscription)
+			subscriptions.POST("/:id/pause", subscriptionHandler.PauseSubscription)
+			subscriptions.POST("/:id/resume", subscriptionHandler.ResumeSubscription)
+			subscriptions.POST("/:id/status", subscriptionHandler.ChangeSubscriptionStatus)
+			subscriptions.POST("/:id/cancel", subscriptionHandler.CancelSubscription)
+			subscriptions.GET("/:id/members", subscriptionHandler.ListMembers)
+			subscriptions.PUT("/:id/members/:user_id", subscriptionHandler.SetMember)
+			subscriptions.DELETE("/:id/members/:user_id", subscriptionHandler.RemoveMember)
+		}
+
+		users := api.Group("/users",
+			rateLimiter.Limit("users"),
+			middleware.RequireScopes(model.ScopeUsersRead, model.ScopeUsersWrite),
+			validate)
+		{
+			users.POST("/", userHandler.CreateUser)
+			users.GET("/", middleware.Authorize(authorizer, authz.ActionUsersRead, authz.ActionUsersWrite, ""), userHandler.ListUsers)
+
+			user := users.Group("/:user_id",
+				middleware.Authorize(authorizer, authz.ActionUsersRead, authz.ActionUsersWrite, "user_id"))
+			user.GET("", userHandler.GetUser)
+			user.PUT("", userHandler.UpdateUser)
+			user.DELETE("", userHandler.DeleteUser)
+			user.GET("/summary", userHandler.GetUserSummary)
+			user.GET("/export", userHandler.ExportUserData)
+			user.DELETE("/data", userHandler.EraseUserData)
+			user.GET("/renewals", subscriptionHandler.ListUpcomingRenewals)
+			user.PUT("/budget", budgetHandler.SetBudget)
+			user.GET("/budget", budgetHandler.GetBudget)
+			user.GET("/budget/status", budgetHandler.GetBudgetStatus)
+		}
+
+		webhooks := api.Group("/webhooks",
+			rateLimiter.Limit("webhooks"),
+			middleware.Authorize(authorizer, authz.ActionWebhooksRead, authz.ActionWebhooksWrite, ""),
+			middleware.RequireScopes(model.ScopeWebhooksRead, model.ScopeWebhooksWrite),
+			validate)
+		{
+			webhooks.POST("/", webhookHandler.CreateWebhook)
+			webhooks.GET("/", webhookHandler.ListWebhooks)
+			webhooks.GET("/:id", webhookHandler.GetWebhook)
+			webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+			webhooks.GET("/:id/deliveries", webhookHandler.ListWebhookDeliveries)
+		}
+
+		apiKeys := api.Group("/api-keys",
+			rateLimiter.Limit("api_keys"),
+			middleware.RequireAdmin(),
+			middleware.RequireScopes(model.ScopeAPIKeysManage, model.ScopeAPIKeysManage),
+			validate)
+		{
+			apiKeys.POST("/", apiKeyHandler.CreateAPIKey)
+			apiKeys.GET("/", apiKeyHandler.ListAPIKeys)
+			apiKeys.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
+		}
+	}
+
+	return r
+}
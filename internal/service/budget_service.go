@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+)
+
+type BudgetService interface {
+	BudgetChecker
+	SetBudget(ctx context.Context, userID string, req *model.SetBudgetRequest) (*model.Budget, error)
+	GetBudget(ctx context.Context, userID string) (*model.Budget, error)
+	GetStatus(ctx context.Context, userID, month string) (*model.BudgetStatus, error)
+}
+
+// BudgetChecker checks whether adding to a user's spend in a month keeps it
+// within their budget. Spend is counted in the tenant found in ctx.
+type BudgetChecker interface {
+	Check(ctx context.Context, userID, month string, additional int) (*model.BudgetWarning, error)
+}
+
+var (
+	ErrBudgetNotFound = errors.New("budget not found")
+	ErrBudgetExceeded = errors.New("monthly budget exceeded")
+)
+
+type budgetService struct {
+	repo          repository.BudgetRepository
+	subscriptions repository.SubscriptionRepository
+}
+
+func NewBudgetService(repo repository.BudgetRepository, subscriptions repository.SubscriptionRepository) BudgetService {
+	return &budgetService{repo: repo, subscriptions: subscriptions}
+}
+
+func (s *budgetService) SetBudget(ctx context.Context, userID string, req *model.SetBudgetRequest) (*model.Budget, error) {
+	budget := &model.Budget{
+		UserID:       userID,
+		MonthlyLimit: *req.MonthlyLimit,
+		Enforcement:  model.BudgetEnforcementWarn,
+	}
+	if req.Enforcement != nil {
+		budget.Enforcement = *req.Enforcement
+	}
+
+	if err := s.repo.Upsert(ctx, budget); err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (s *budgetService) GetBudget(ctx context.Context, userID string) (*model.Budget, error) {
+	budget, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if budget == nil {
+		return nil, ErrBudgetNotFound
+	}
+	return budget, nil
+}
+
+func (s *budgetService) GetStatus(ctx context.Context, userID, month string) (*model.BudgetStatus, error) {
+	if month == "" {
+		month = currentMonth()
+	}
+	if !isValidDateFormat(month) {
+		return nil, &ValidationError{Field: "month", Message: "invalid month format, expected MM-YYYY"}
+	}
+
+	budget, err := s.GetBudget(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	spend, err := s.subscriptions.GetTotalPrice(ctx, userID, "", month, month)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.BudgetStatus{
+		UserID:       userID,
+		Month:        month,
+		MonthlyLimit: budget.MonthlyLimit,
+		Spend:        spend,
+		Remaining:    max(budget.MonthlyLimit-spend, 0),
+		Exceeded:     spend > budget.MonthlyLimit,
+		Enforcement:  budget.Enforcement,
+	}, nil
+}
+
+// Check returns a warning when the user's spend in month plus additional
+// goes over their budget. Under reject enforcement it also returns an error
+// wrapping ErrBudgetExceeded. Users without a budget are never limited.
+func (s *budgetService) Check(ctx context.Context, userID, month string, additional int) (*model.BudgetWarning, error) {
+	budget, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil || budget == nil {
+		return nil, err
+	}
+
+	spend, err := s.subscriptions.GetTotalPrice(ctx, userID, "", month, month)
+	if err != nil {
+		return nil, err
+	}
+
+	projected := spend + additional
+	if projected <= budget.MonthlyLimit {
+		return nil, nil
+	}
+
+	warning := &model.BudgetWarning{
+		Month:          month,
+		MonthlyLimit:   budget.MonthlyLimit,
+		ProjectedSpend: projected,
+		ExceededBy:     projected - budget.MonthlyLimit,
+	}
+	if budget.Enforcement == model.BudgetEnforcementReject {
+		return warning, fmt.Errorf("%w: projected spend %d for %s is over the limit of %d",
+			ErrBudgetExceeded, projected, month, budget.MonthlyLimit)
+	}
+	return warning, nil
+}
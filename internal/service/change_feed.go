@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+)
+
+// ChangeFeed fans subscription changes out to streaming clients.
+type ChangeFeed struct {
+	mu          sync.Mutex
+	subscribers map[*feedSubscriber]struct{}
+	closed      bool
+}
+
+type feedSubscriber struct {
+	tenantID string
+	userID   string
+	changes  chan model.SubscriptionChange
+}
+
+func NewChangeFeed() *ChangeFeed {
+	return &ChangeFeed{subscribers: make(map[*feedSubscriber]struct{})}
+}
+
+// Run broadcasts changes until ctx is cancelled or changes is closed, and
+// then closes every subscriber's channel, so that streams end and the
+// server can shut down without waiting for clients to disconnect.
+func (f *ChangeFeed) Run(ctx context.Context, changes <-chan model.SubscriptionChange) {
+	defer f.close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			f.broadcast(change)
+		}
+	}
+}
+
+// Subscribe returns the changes of the subscriptions userID owns or is a
+// member of in tenantID, or of all the tenant's subscriptions when userID
+// is empty, and a function
+// that stops them. The channel is closed when the feed stops.
+func (f *ChangeFeed) Subscribe(tenantID, userID string) (<-chan model.SubscriptionChange, func()) {
+	sub := &feedSubscriber{
+		tenantID: tenantID,
+		userID:   userID,
+		changes:  make(chan model.SubscriptionChange, 16),
+	}
+
+	f.mu.Lock()
+	if f.closed {
+		close(sub.changes)
+	} else {
+		f.subscribers[sub] = struct{}{}
+	}
+	f.mu.Unlock()
+
+	return sub.changes, func() {
+		f.mu.Lock()
+		delete(f.subscribers, sub)
+		f.mu.Unlock()
+	}
+}
+
+func (f *ChangeFeed) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	for sub := range f.subscribers {
+		close(sub.changes)
+		delete(f.subscribers, sub)
+	}
+}
+
+// broadcast never blocks: a client that has fallen a full buffer behind
+// misses the change rather than stalling everyone else.
+func (f *ChangeFeed) broadcast(change model.SubscriptionChange) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for sub := range f.subscribers {
+		if sub.tenantID != change.TenantID || !sub.follows(change) {
+			continue
+		}
+		select {
+		case sub.changes <- change:
+		default:
+		}
+	}
+}
+
+func (sub *feedSubscriber) follows(change model.SubscriptionChange) bool {
+	if sub.userID == "" || sub.userID == change.UserID {
+		return true
+	}
+	for _, memberID := range change.MemberIDs {
+		if memberID == sub.userID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/t5129001t-jpg/subscription-service/internal/logging"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+)
+
+// HealthService decides whether the service is ready for traffic.
+type HealthService interface {
+	Readiness(ctx context.Context) (*model.Readiness, bool)
+	// Drain marks the service as not ready, so that load balancers stop
+	// sending requests before the server shuts down.
+	Drain()
+}
+
+type healthService struct {
+	repo             repository.HealthRepository
+	migrationVersion int64
+	timeout          time.Duration
+	draining         atomic.Bool
+}
+
+// NewHealthService checks that the database answers within timeout and has
+// at least migrationVersion, the latest migration this build ships,
+// applied. A newer version is accepted, so that replicas of the previous
+// build stay ready while a rolling deploy migrates ahead of them.
+func NewHealthService(repo repository.HealthRepository, migrationVersion int64, timeout time.Duration) HealthService {
+	return &healthService{repo: repo, migrationVersion: migrationVersion, timeout: timeout}
+}
+
+func (s *healthService) Readiness(ctx context.Context) (*model.Readiness, bool) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	checks := map[string]string{
+		"database":   "ok",
+		"migrations": "ok",
+		"shutdown":   "ok",
+	}
+	ready := true
+	fail := func(check, reason string) {
+		checks[check] = reason
+		ready = false
+	}
+
+	if s.draining.Load() {
+		fail("shutdown", "shutting down")
+	}
+	// Errors are logged rather than returned, as readiness is public.
+	if err := s.repo.Ping(ctx); err != nil {
+		logging.FromContext(ctx).Error("readiness check: database unreachable", "error", err)
+		fail("database", "unreachable")
+		fail("migrations", "unknown")
+	} else if version, err := s.repo.MigrationVersion(ctx); err != nil {
+		logging.FromContext(ctx).Error("readiness check: migration version unknown", "error", err)
+		fail("migrations", "unknown")
+	} else if version < s.migrationVersion {
+		fail("migrations", fmt.Sprintf("at version %d, expected %d", version, s.migrationVersion))
+	}
+
+	readiness := &model.Readiness{Status: "ready", Checks: checks}
+	if !ready {
+		readiness.Status = "not ready"
+	}
+	return readiness, ready
+}
+
+func (s *healthService) Drain() {
+	s.draining.Store(true)
+}
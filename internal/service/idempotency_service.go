@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+)
+
+// IdempotencyService lets a client retry a request under the same
+// Idempotency-Key and get the first response back instead of repeating
+// the request's effect.
+type IdempotencyService interface {
+	// Begin claims key for a request with requestHash. It returns the
+	// stored response of an earlier request with the same key, or nil when
+	// the caller is to handle the request and then Complete or Release the
+	// key.
+	Begin(ctx context.Context, scope, key, requestHash string) (*model.StoredResponse, error)
+	Complete(ctx context.Context, scope, key string, response *model.StoredResponse) error
+	Release(ctx context.Context, scope, key string) error
+	// RunCleanup deletes expired keys every interval until ctx is cancelled.
+	RunCleanup(ctx context.Context, interval time.Duration)
+}
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was used for a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is in progress")
+	ErrInvalidIdempotencyKey    = errors.New("idempotency key must be 1 to 255 characters")
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// idempotencyAbandonAfter is how long an unfinished request holds its
+	// key, in case the replica handling it died.
+	idempotencyAbandonAfter = time.Minute
+)
+
+type idempotencyService struct {
+	repo repository.IdempotencyRepository
+	ttl  time.Duration
+}
+
+// NewIdempotencyService keeps responses for ttl after the first request.
+func NewIdempotencyService(repo repository.IdempotencyRepository, ttl time.Duration) IdempotencyService {
+	return &idempotencyService{repo: repo, ttl: ttl}
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, scope, key, requestHash string) (*model.StoredResponse, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, ErrInvalidIdempotencyKey
+	}
+
+	record := &model.IdempotencyRecord{
+		Scope:       scope,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	}
+	reserved, err := s.repo.Reserve(ctx, record, idempotencyAbandonAfter)
+	if err != nil || reserved {
+		return nil, err
+	}
+
+	existing, err := s.repo.Get(ctx, scope, key)
+	if err != nil {
+		return nil, err
+	}
+	// A record released between the two queries is still being retried.
+	if existing == nil {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+	if existing.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if existing.StatusCode == nil {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	response := &model.StoredResponse{StatusCode: *existing.StatusCode, Body: existing.ResponseBody}
+	if existing.ContentType != nil {
+		response.ContentType = *existing.ContentType
+	}
+	return response, nil
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, scope, key string, response *model.StoredResponse) error {
+	return s.repo.Complete(ctx, scope, key, response)
+}
+
+// Release forgets key, so that a failed request can be retried.
+func (s *idempotencyService) Release(ctx context.Context, scope, key string) error {
+	return s.repo.Delete(ctx, scope, key)
+}
+
+func (s *idempotencyService) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.repo.DeleteExpired(ctx)
+		}
+	}
+}
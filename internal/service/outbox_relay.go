@@ -0,0 +1,135 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+)
+
+// OutboxPublisher delivers outbox events to other services. Publish must
+// return an error unless the event has been accepted, as the relay marks
+// it published as soon as Publish returns nil.
+type OutboxPublisher interface {
+	Publish(ctx context.Context, event model.OutboxEvent) error
+}
+
+// OutboxRelay drains the outbox to an OutboxPublisher.
+type OutboxRelay struct {
+	repo         repository.OutboxRepository
+	publisher    OutboxPublisher
+	pollInterval time.Duration
+	batchSize    int
+}
+
+func NewOutboxRelay(repo repository.OutboxRepository, publisher OutboxPublisher, pollInterval time.Duration, batchSize int) *OutboxRelay {
+	return &OutboxRelay{
+		repo:         repo,
+		publisher:    publisher,
+		pollInterval: pollInterval,
+		batchSize:    batchSize,
+	}
+}
+
+// Run relays until ctx is cancelled. Full batches are followed by another
+// one straight away, so a backlog drains without waiting for the ticker.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				published, err := r.RelayBatch(ctx)
+				if err != nil {
+					slog.Error("failed to relay outbox events", "error", err)
+					break
+				}
+				if published < r.batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// RelayBatch publishes one batch of outbox events and returns how many were
+// published.
+func (r *OutboxRelay) RelayBatch(ctx context.Context) (int, error) {
+	return r.repo.Relay(ctx, r.batchSize, func(event model.OutboxEvent) error {
+		return r.publisher.Publish(ctx, event)
+	})
+}
+
+// WriterPublisher writes every event as a line of JSON, e.g. to stdout or
+// an append-only file.
+type WriterPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterPublisher(w io.Writer) *WriterPublisher {
+	return &WriterPublisher{w: w}
+}
+
+func (p *WriterPublisher) Publish(_ context.Context, event model.OutboxEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.w.Write(append(line, '\n'))
+	return err
+}
+
+// HTTPPublisher POSTs every event as JSON to a fixed URL. Any non-2xx
+// response counts as a failure.
+type HTTPPublisher struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPPublisher(url string, timeout time.Duration) *HTTPPublisher {
+	return &HTTPPublisher{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (p *HTTPPublisher) Publish(ctx context.Context, event model.OutboxEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Id", strconv.FormatInt(event.ID, 10))
+	req.Header.Set("X-Event-Type", event.EventType)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("publisher endpoint responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package service
+
+// maxPageSize caps the limit of every listing.
+const maxPageSize = 100
+
+// Page returns the limit and offset a listing applies: limit defaults to
+// 10 and is capped at 100, and a negative offset counts from the start.
+func Page(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/t5129001t-jpg/subscription-service/internal/config"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+)
+
+// RateLimitStore keeps a token bucket per client key. Peek tells whether
+// Take would be allowed, without taking a token.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit config.RateLimit) (*RateLimitDecision, error)
+	Peek(ctx context.Context, key string, limit config.RateLimit) (*RateLimitDecision, error)
+}
+
+// RateLimitDecision is the outcome of taking a token. RetryAfter is the
+// wait for the next token of a denied request, Reset the time until the
+// bucket is full again.
+type RateLimitDecision struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// pruneEvery is how many takes pass between sweeps of idle buckets.
+const pruneEvery = 1000
+
+func NewRateLimitStore(kind string, repo repository.RateLimitRepository) (RateLimitStore, error) {
+	switch kind {
+	case "memory":
+		return NewMemoryRateLimitStore(), nil
+	case "postgres":
+		return &postgresRateLimitStore{repo: repo}, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", kind)
+	}
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	takes   int
+}
+
+// NewMemoryRateLimitStore keeps buckets in this process only, so each
+// replica enforces its own limits.
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+func (s *memoryRateLimitStore) Take(ctx context.Context, key string, limit config.RateLimit) (*RateLimitDecision, error) {
+	now := time.Now()
+	perSecond := perSecond(limit)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.takes++
+	if s.takes%pruneEvery == 0 {
+		s.prune(now)
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = bucket
+	}
+	available := math.Min(float64(limit.Burst), bucket.tokens+now.Sub(bucket.updated).Seconds()*perSecond)
+
+	allowed := available >= 1
+	if allowed {
+		available--
+	}
+	bucket.tokens = available
+	bucket.updated = now
+
+	return decide(allowed, available, limit), nil
+}
+
+func (s *memoryRateLimitStore) Peek(ctx context.Context, key string, limit config.RateLimit) (*RateLimitDecision, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	available := float64(limit.Burst)
+	if bucket, ok := s.buckets[key]; ok {
+		available = math.Min(available, bucket.tokens+now.Sub(bucket.updated).Seconds()*perSecond(limit))
+	}
+	return decide(available >= 1, available, limit), nil
+}
+
+// prune drops buckets unused for a day. By then they have refilled, and a
+// full bucket behaves exactly like a missing one.
+func (s *memoryRateLimitStore) prune(now time.Time) {
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updated) > 24*time.Hour {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+type postgresRateLimitStore struct {
+	repo  repository.RateLimitRepository
+	takes atomic.Int64
+}
+
+func (s *postgresRateLimitStore) Take(ctx context.Context, key string, limit config.RateLimit) (*RateLimitDecision, error) {
+	if s.takes.Add(1)%pruneEvery == 0 {
+		// The sweep outlives the request that triggered it.
+		go s.repo.Prune(context.WithoutCancel(ctx), 24*time.Hour)
+	}
+
+	allowed, tokens, err := s.repo.Take(ctx, key, limit.Burst, perSecond(limit))
+	if err != nil {
+		return nil, err
+	}
+	return decide(allowed, tokens, limit), nil
+}
+
+func (s *postgresRateLimitStore) Peek(ctx context.Context, key string, limit config.RateLimit) (*RateLimitDecision, error) {
+	tokens, err := s.repo.Peek(ctx, key, limit.Burst, perSecond(limit))
+	if err != nil {
+		return nil, err
+	}
+	return decide(tokens >= 1, tokens, limit), nil
+}
+
+func perSecond(limit config.RateLimit) float64 {
+	return float64(limit.PerMinute) / 60
+}
+
+func decide(allowed bool, tokens float64, limit config.RateLimit) *RateLimitDecision {
+	rate := perSecond(limit)
+	decision := &RateLimitDecision{
+		Allowed:   allowed,
+		Remaining: int(math.Max(tokens, 0)),
+		Reset:     time.Duration((float64(limit.Burst) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		decision.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return decision
+}
@@ -1,68 +1,193 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"regexp"
+	"sort"
 	"time"
 
+	"github.com/t5129001t-jpg/subscription-service/internal/authz"
+	"github.com/t5129001t-jpg/subscription-service/internal/logging"
 	"github.com/t5129001t-jpg/subscription-service/internal/model"
 	"github.com/t5129001t-jpg/subscription-service/internal/repository"
 )
 
+// SubscriptionService methods act for the caller found in ctx and check
+// each operation with the Authorizer.
 type SubscriptionService interface {
-	Create(req *model.CreateSubscriptionRequest) (*model.Subscription, error)
-	GetByID(id string) (*model.Subscription, error)
-	Update(id string, req *model.UpdateSubscriptionRequest) error
-	Delete(id string) error
-	List(filter model.SubscriptionFilter) ([]model.Subscription, int, error)
-	GetTotalPrice(filter model.SubscriptionFilter) (int, error)
+	Create(ctx context.Context, req *model.CreateSubscriptionRequest) (*model.Subscription, error)
+	GetByID(ctx context.Context, id string) (*model.Subscription, error)
+	Update(ctx context.Context, id string, req *model.UpdateSubscriptionRequest) (*model.BudgetWarning, error)
+	Delete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) (*model.Subscription, error)
+	List(ctx context.Context, filter model.SubscriptionFilter) ([]model.Subscription, int, error)
+	GetTotalPrice(ctx context.Context, filter model.SubscriptionFilter) (int, error)
+	Pause(ctx context.Context, id string, req *model.PauseSubscriptionRequest) (*model.Subscription, error)
+	Resume(ctx context.Context, id string, req *model.ResumeSubscriptionRequest) (*model.Subscription, error)
+	ChangeStatus(ctx context.Context, id string, req *model.ChangeStatusRequest) (*model.Subscription, error)
+	Cancel(ctx context.Context, id string, req *model.CancelSubscriptionRequest) (*model.Subscription, error)
+	UpcomingRenewals(ctx context.Context, userID string, withinDays int) ([]model.Renewal, error)
+	DueRenewals(ctx context.Context, daysAhead int) ([]model.Renewal, error)
+	ListOverlaps(ctx context.Context, userID string) ([]model.SubscriptionOverlap, error)
+	ListMembers(ctx context.Context, id string) ([]model.SubscriptionMember, error)
+	SetMember(ctx context.Context, id, userID string, req *model.SetMemberRequest) (*model.SubscriptionMember, error)
+	RemoveMember(ctx context.Context, id, userID string) error
+}
+
+// EventPublisher receives subscription change events.
+type EventPublisher interface {
+	Publish(ctx context.Context, eventType string, data interface{}) error
+}
+
+var (
+	ErrSubscriptionNotFound  = errors.New("subscription not found")
+	ErrAlreadyPaused         = errors.New("subscription is already paused")
+	ErrNotPaused             = errors.New("subscription is not paused")
+	ErrInvalidTransition     = errors.New("invalid status transition")
+	ErrDuplicateSubscription = errors.New("overlapping subscription to the same service exists")
+	ErrMemberNotFound        = errors.New("subscription member not found")
+)
+
+// DuplicateSubscriptionError identifies the subscription a new or updated
+// one would overlap with.
+type DuplicateSubscriptionError struct {
+	ConflictingID string
+}
+
+func (e *DuplicateSubscriptionError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrDuplicateSubscription, e.ConflictingID)
+}
+
+func (e *DuplicateSubscriptionError) Unwrap() error {
+	return ErrDuplicateSubscription
+}
+
+// allowedTransitions lists the status changes a client may request.
+// Trials ending and subscriptions expiring past end_date happen by
+// calendar, so nothing transitions into expired on request.
+var allowedTransitions = map[string][]string{
+	model.SubscriptionStatusTrial:  {model.SubscriptionStatusActive, model.SubscriptionStatusCancelled},
+	model.SubscriptionStatusActive: {model.SubscriptionStatusPaused, model.SubscriptionStatusCancelled},
+	model.SubscriptionStatusPaused: {model.SubscriptionStatusActive, model.SubscriptionStatusCancelled},
+}
+
+// ValidationError reports a request field that failed a business rule.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
 }
 
 type subscriptionService struct {
-	repo repository.SubscriptionRepository
+	repo    repository.SubscriptionRepository
+	events  EventPublisher
+	budgets BudgetChecker
+	authz   authz.Authorizer
 }
 
-func NewSubscriptionService(repo repository.SubscriptionRepository) SubscriptionService {
-	return &subscriptionService{repo: repo}
+// NewSubscriptionService creates the service. Without an authorizer every
+// caller may do everything.
+func NewSubscriptionService(
+	repo repository.SubscriptionRepository,
+	events EventPublisher,
+	budgets BudgetChecker,
+	authorizer authz.Authorizer,
+) SubscriptionService {
+	return &subscriptionService{repo: repo, events: events, budgets: budgets, authz: authorizer}
 }
 
-func (s *subscriptionService) Create(req *model.CreateSubscriptionRequest) (*model.Subscription, error) {
+func (s *subscriptionService) Create(ctx context.Context, req *model.CreateSubscriptionRequest) (*model.Subscription, error) {
 	if !isValidDateFormat(req.StartDate) {
-		return nil, errors.New("invalid start_date format, expected MM-YYYY")
+		return nil, &ValidationError{Field: "start_date", Message: "invalid start_date format, expected MM-YYYY"}
 	}
 
 	if req.EndDate != nil && !isValidDateFormat(*req.EndDate) {
-		return nil, errors.New("invalid end_date format, expected MM-YYYY")
+		return nil, &ValidationError{Field: "end_date", Message: "invalid end_date format, expected MM-YYYY"}
 	}
 
 	if req.EndDate != nil {
 		if !isEndDateAfterStartDate(req.StartDate, *req.EndDate) {
-			return nil, errors.New("end_date must be after or equal to start_date")
+			return nil, &ValidationError{Field: "end_date", Message: "end_date must be after or equal to start_date"}
 		}
 	}
 
+	status := model.SubscriptionStatusActive
+	if req.TrialEnd != nil {
+		if !isValidDateFormat(*req.TrialEnd) {
+			return nil, &ValidationError{Field: "trial_end", Message: "invalid trial_end format, expected MM-YYYY"}
+		}
+		if !isEndDateAfterStartDate(req.StartDate, *req.TrialEnd) {
+			return nil, &ValidationError{Field: "trial_end", Message: "trial_end must be after or equal to start_date"}
+		}
+		if req.EndDate != nil && !isEndDateAfterStartDate(*req.TrialEnd, *req.EndDate) {
+			return nil, &ValidationError{Field: "trial_end", Message: "trial_end must be before or equal to end_date"}
+		}
+		status = model.SubscriptionStatusTrial
+	}
+
+	billingDay := 1
+	if req.BillingDay != nil {
+		billingDay = *req.BillingDay
+	}
+
 	sub := &model.Subscription{
 		ServiceName: req.ServiceName,
 		Price:       req.Price,
 		UserID:      req.UserID,
 		StartDate:   req.StartDate,
 		EndDate:     req.EndDate,
+		TrialEnd:    req.TrialEnd,
+		Status:      status,
+		BillingDay:  billingDay,
+	}
+
+	if err := s.authorize(ctx, authz.ActionWrite, sub.UserID); err != nil {
+		return nil, err
+	}
+
+	if !req.AllowOverlap {
+		if err := s.checkOverlap(ctx, sub); err != nil {
+			return nil, err
+		}
+	}
+
+	warning, err := s.checkBudget(ctx, nil, sub)
+	if err != nil {
+		return nil, err
 	}
 
-	err := s.repo.Create(sub)
-	return sub, err
+	if err := s.repo.Create(ctx, sub); err != nil {
+		return nil, userReferenceError(err)
+	}
+	sub.BudgetWarning = warning
+
+	s.publish(ctx, model.EventSubscriptionCreated, sub)
+	return sub, nil
 }
 
-func (s *subscriptionService) GetByID(id string) (*model.Subscription, error) {
+func (s *subscriptionService) GetByID(ctx context.Context, id string) (*model.Subscription, error) {
 	if id == "" {
-		return nil, errors.New("id is required")
+		return nil, &ValidationError{Field: "id", Message: "id is required"}
+	}
+
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil || sub == nil {
+		return nil, err
+	}
+	if err := s.authorizeRead(ctx, sub); err != nil {
+		return nil, err
 	}
-	return s.repo.GetByID(id)
+	return sub, nil
 }
 
-func (s *subscriptionService) Update(id string, req *model.UpdateSubscriptionRequest) error {
+func (s *subscriptionService) Update(ctx context.Context, id string, req *model.UpdateSubscriptionRequest) (*model.BudgetWarning, error) {
 	if id == "" {
-		return errors.New("id is required")
+		return nil, &ValidationError{Field: "id", Message: "id is required"}
 	}
 
 	updates := make(map[string]interface{})
@@ -78,61 +203,823 @@ func (s *subscriptionService) Update(id string, req *model.UpdateSubscriptionReq
 	}
 	if req.StartDate != nil {
 		if !isValidDateFormat(*req.StartDate) {
-			return errors.New("invalid start_date format")
+			return nil, &ValidationError{Field: "start_date", Message: "invalid start_date format, expected MM-YYYY"}
 		}
 		updates["start_date"] = *req.StartDate
 	}
 	if req.EndDate != nil {
 		if !isValidDateFormat(*req.EndDate) {
-			return errors.New("invalid end_date format")
+			return nil, &ValidationError{Field: "end_date", Message: "invalid end_date format, expected MM-YYYY"}
 		}
 		updates["end_date"] = *req.EndDate
 	}
+	if req.TrialEnd != nil {
+		if !isValidDateFormat(*req.TrialEnd) {
+			return nil, &ValidationError{Field: "trial_end", Message: "invalid trial_end format, expected MM-YYYY"}
+		}
+		updates["trial_end"] = *req.TrialEnd
+	}
+	if req.BillingDay != nil {
+		updates["billing_day"] = *req.BillingDay
+	}
+
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err := s.authorize(ctx, authz.ActionWrite, current.UserID); err != nil {
+		return nil, err
+	}
+	if err := checkUpdatedPeriod(current, req); err != nil {
+		return nil, err
+	}
+	// Handing a subscription over needs the right to write the new owner's
+	// subscriptions as well.
+	if req.UserID != nil {
+		if err := s.authorize(ctx, authz.ActionWrite, *req.UserID); err != nil {
+			return nil, err
+		}
+	}
+	if req.Price != nil {
+		members, err := s.repo.ListMembers(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if membersShare(members, *req.Price) > *req.Price {
+			return nil, &ValidationError{Field: "price", Message: "price is below the members' shares"}
+		}
+	}
+
+	warning, err := s.checkUpdate(ctx, id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, id, updates); err != nil {
+		return nil, userReferenceError(err)
+	}
 
-	return s.repo.Update(id, updates)
+	if s.events != nil {
+		if _, err := s.updated(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	return warning, nil
 }
 
-func (s *subscriptionService) Delete(id string) error {
+// checkUpdatedPeriod applies Create's date range checks to the subscription
+// as it will be after req. The trial may only be moved while it runs.
+func checkUpdatedPeriod(current *model.Subscription, req *model.UpdateSubscriptionRequest) error {
+	if req.TrialEnd != nil && current.Status != model.SubscriptionStatusTrial {
+		return fmt.Errorf("%w: trial_end can only change during a trial, not when %s", ErrInvalidTransition, current.Status)
+	}
+
+	startDate, endDate, trialEnd := current.StartDate, current.EndDate, current.TrialEnd
+	if req.StartDate != nil {
+		startDate = *req.StartDate
+	}
+	if req.EndDate != nil {
+		endDate = req.EndDate
+	}
+	if req.TrialEnd != nil {
+		trialEnd = req.TrialEnd
+	}
+
+	if endDate != nil && !isEndDateAfterStartDate(startDate, *endDate) {
+		return &ValidationError{Field: "end_date", Message: "end_date must be after or equal to start_date"}
+	}
+	if trialEnd != nil {
+		if !isEndDateAfterStartDate(startDate, *trialEnd) {
+			return &ValidationError{Field: "trial_end", Message: "trial_end must be after or equal to start_date"}
+		}
+		if endDate != nil && !isEndDateAfterStartDate(*trialEnd, *endDate) {
+			return &ValidationError{Field: "trial_end", Message: "trial_end must be before or equal to end_date"}
+		}
+	}
+	return nil
+}
+
+// checkUpdate runs the overlap and budget checks against the subscription
+// as it will be after the update, when the update touches anything they
+// depend on.
+func (s *subscriptionService) checkUpdate(ctx context.Context, id string, req *model.UpdateSubscriptionRequest) (*model.BudgetWarning, error) {
+	periodChanged := req.UserID != nil || req.StartDate != nil || req.EndDate != nil
+	checkOverlap := !req.AllowOverlap && (periodChanged || req.ServiceName != nil)
+	checkBudget := s.budgets != nil && (periodChanged || req.Price != nil || req.TrialEnd != nil)
+	if !checkOverlap && !checkBudget {
+		return nil, nil
+	}
+
+	old, err := s.repo.GetByID(ctx, id)
+	if err != nil || old == nil {
+		return nil, err
+	}
+
+	updated := *old
+	if req.ServiceName != nil {
+		updated.ServiceName = *req.ServiceName
+	}
+	if req.Price != nil {
+		updated.Price = *req.Price
+	}
+	if req.UserID != nil {
+		updated.UserID = *req.UserID
+	}
+	if req.StartDate != nil {
+		updated.StartDate = *req.StartDate
+	}
+	if req.EndDate != nil {
+		updated.EndDate = req.EndDate
+	}
+	if req.TrialEnd != nil {
+		updated.TrialEnd = req.TrialEnd
+	}
+
+	if checkOverlap {
+		if err := s.checkOverlap(ctx, &updated); err != nil {
+			return nil, err
+		}
+	}
+	if checkBudget {
+		return s.checkBudget(ctx, old, &updated)
+	}
+	return nil, nil
+}
+
+// checkOverlap rejects a subscription whose period overlaps another one of
+// the same user to the same service.
+func (s *subscriptionService) checkOverlap(ctx context.Context, sub *model.Subscription) error {
+	conflicting, err := s.repo.FindOverlapping(ctx, sub)
+	if err != nil {
+		return err
+	}
+	if conflicting != nil {
+		return &DuplicateSubscriptionError{ConflictingID: conflicting.ID}
+	}
+	return nil
+}
+
+// checkBudget projects the user's spend for the first month from now on in
+// which sub is billed, replacing what old contributed to that month.
+func (s *subscriptionService) checkBudget(ctx context.Context, old, sub *model.Subscription) (*model.BudgetWarning, error) {
+	if s.budgets == nil {
+		return nil, nil
+	}
+
+	month, _ := time.Parse("01-2006", currentMonth())
+	start, _ := time.Parse("01-2006", sub.StartDate)
+	if start.After(month) {
+		month = start
+	}
+	if sub.TrialEnd != nil {
+		trialEnd, _ := time.Parse("01-2006", *sub.TrialEnd)
+		if !trialEnd.Before(month) {
+			month = trialEnd.AddDate(0, 1, 0)
+		}
+	}
+	if !isBillableMonth(*sub, nil, month) {
+		return nil, nil
+	}
+
+	additional := sub.Price
+	if old != nil && old.UserID == sub.UserID {
+		pauses, err := s.repo.ListPauses(ctx, old.ID)
+		if err != nil {
+			return nil, err
+		}
+		oldStart, _ := time.Parse("01-2006", old.StartDate)
+		if !month.Before(oldStart) && isBillableMonth(*old, pauses, month) {
+			additional -= old.Price
+		}
+	}
+	if additional <= 0 {
+		return nil, nil
+	}
+
+	return s.budgets.Check(ctx, sub.UserID, month.Format("01-2006"), additional)
+}
+
+func (s *subscriptionService) Delete(ctx context.Context, id string) error {
 	if id == "" {
-		return errors.New("id is required")
+		return &ValidationError{Field: "id", Message: "id is required"}
+	}
+
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if sub == nil {
+		return ErrSubscriptionNotFound
+	}
+	if err := s.authorize(ctx, authz.ActionDelete, sub.UserID); err != nil {
+		return err
 	}
-	return s.repo.Delete(id)
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.publish(ctx, model.EventSubscriptionDeleted, sub)
+	return nil
 }
 
-func (s *subscriptionService) List(filter model.SubscriptionFilter) ([]model.Subscription, int, error) {
-	if filter.Limit <= 0 {
-		filter.Limit = 10
+// Restore brings back a deleted subscription, unless it would now overlap
+// another one of the same user to the same service.
+func (s *subscriptionService) Restore(ctx context.Context, id string) (*model.Subscription, error) {
+	sub, err := s.repo.GetDeleted(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	if filter.Limit > 100 {
-		filter.Limit = 100
+	if sub == nil {
+		return nil, ErrSubscriptionNotFound
 	}
-	if filter.Offset < 0 {
-		filter.Offset = 0
+	if err := s.authorize(ctx, authz.ActionRestore, sub.UserID); err != nil {
+		return nil, err
+	}
+	if err := s.checkOverlap(ctx, sub); err != nil {
+		return nil, err
 	}
 
-	return s.repo.List(filter)
+	if err := s.repo.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.updated(ctx, id)
 }
 
-func (s *subscriptionService) GetTotalPrice(filter model.SubscriptionFilter) (int, error) {
+func (s *subscriptionService) List(ctx context.Context, filter model.SubscriptionFilter) ([]model.Subscription, int, error) {
+	filter.Limit, filter.Offset = Page(filter.Limit, filter.Offset)
+
+	userID, err := s.readableUser(ctx, filter.UserID)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter.UserID = userID
+
+	return s.repo.List(ctx, filter)
+}
+
+func (s *subscriptionService) GetTotalPrice(ctx context.Context, filter model.SubscriptionFilter) (int, error) {
+	userID, err := s.readableUser(ctx, filter.UserID)
+	if err != nil {
+		return 0, err
+	}
+	filter.UserID = userID
+
 	if filter.Month != "" {
 		if !isValidDateFormat(filter.Month) {
-			return 0, errors.New("invalid month format")
+			return 0, &ValidationError{Field: "month", Message: "invalid month format, expected MM-YYYY"}
 		}
-		return s.repo.GetTotalPrice(filter.UserID, filter.ServiceName, filter.Month, filter.Month)
+		return s.repo.GetTotalPrice(ctx, filter.UserID, filter.ServiceName, filter.Month, filter.Month)
 	}
 
 	if filter.StartMonth == "" || filter.EndMonth == "" {
-		return 0, errors.New("both start_month and end_month must be provided")
+		return 0, &ValidationError{Field: "start_month", Message: "both start_month and end_month must be provided"}
 	}
 
 	if !isValidDateFormat(filter.StartMonth) {
-		return 0, errors.New("invalid start_month format")
+		return 0, &ValidationError{Field: "start_month", Message: "invalid start_month format, expected MM-YYYY"}
 	}
 	if !isValidDateFormat(filter.EndMonth) {
-		return 0, errors.New("invalid end_month format")
+		return 0, &ValidationError{Field: "end_month", Message: "invalid end_month format, expected MM-YYYY"}
+	}
+
+	return s.repo.GetTotalPrice(ctx, filter.UserID, filter.ServiceName, filter.StartMonth, filter.EndMonth)
+}
+
+func (s *subscriptionService) Pause(ctx context.Context, id string, req *model.PauseSubscriptionRequest) (*model.Subscription, error) {
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err := s.authorize(ctx, authz.ActionWrite, sub.UserID); err != nil {
+		return nil, err
+	}
+	if sub.Status == model.SubscriptionStatusPaused {
+		return nil, ErrAlreadyPaused
+	}
+	if !canTransition(sub.Status, model.SubscriptionStatusPaused) {
+		return nil, transitionError(sub.Status, model.SubscriptionStatusPaused)
+	}
+
+	month := currentMonth()
+	if req.Month != nil {
+		month = *req.Month
+	}
+	if !isValidDateFormat(month) {
+		return nil, &ValidationError{Field: "month", Message: "invalid month format, expected MM-YYYY"}
+	}
+	if !isEndDateAfterStartDate(sub.StartDate, month) {
+		return nil, &ValidationError{Field: "month", Message: "month must be after or equal to start_date"}
+	}
+	if sub.EndDate != nil && !isEndDateAfterStartDate(month, *sub.EndDate) {
+		return nil, &ValidationError{Field: "month", Message: "month must be before or equal to end_date"}
 	}
 
-	return s.repo.GetTotalPrice(filter.UserID, filter.ServiceName, filter.StartMonth, filter.EndMonth)
+	open, err := s.repo.GetOpenPause(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if open != nil {
+		return nil, ErrAlreadyPaused
+	}
+
+	// A pause opened concurrently since the check above.
+	if _, err := s.repo.CreatePause(ctx, id, month); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrAlreadyPaused
+		}
+		return nil, err
+	}
+	return s.updated(ctx, id)
+}
+
+// Resume closes the open pause so that billing restarts from the given
+// month. Resuming in the month the pause started discards the pause.
+func (s *subscriptionService) Resume(ctx context.Context, id string, req *model.ResumeSubscriptionRequest) (*model.Subscription, error) {
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err := s.authorize(ctx, authz.ActionWrite, sub.UserID); err != nil {
+		return nil, err
+	}
+
+	month := currentMonth()
+	if req.Month != nil {
+		month = *req.Month
+	}
+	if !isValidDateFormat(month) {
+		return nil, &ValidationError{Field: "month", Message: "invalid month format, expected MM-YYYY"}
+	}
+
+	open, err := s.repo.GetOpenPause(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if open == nil {
+		return nil, ErrNotPaused
+	}
+	if !isEndDateAfterStartDate(open.StartMonth, month) {
+		return nil, &ValidationError{Field: "month", Message: "month must be after or equal to the month the pause started"}
+	}
+
+	lastPaused := previousMonth(month)
+	if isEndDateAfterStartDate(open.StartMonth, lastPaused) {
+		err = s.repo.ClosePause(ctx, open.ID, lastPaused)
+	} else {
+		err = s.repo.DeletePause(ctx, open.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.updated(ctx, id)
+}
+
+// ChangeStatus moves a subscription to the requested status when the
+// transition from its current status is allowed.
+func (s *subscriptionService) ChangeStatus(ctx context.Context, id string, req *model.ChangeStatusRequest) (*model.Subscription, error) {
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err := s.authorize(ctx, authz.ActionWrite, sub.UserID); err != nil {
+		return nil, err
+	}
+	if !canTransition(sub.Status, req.Status) {
+		return nil, transitionError(sub.Status, req.Status)
+	}
+
+	switch {
+	case req.Status == model.SubscriptionStatusPaused:
+		return s.Pause(ctx, id, &model.PauseSubscriptionRequest{})
+	case req.Status == model.SubscriptionStatusCancelled:
+		return s.Cancel(ctx, id, &model.CancelSubscriptionRequest{})
+	case sub.Status == model.SubscriptionStatusPaused:
+		return s.Resume(ctx, id, &model.ResumeSubscriptionRequest{})
+	}
+
+	// Ending a trial early makes the current month the first billed one.
+	updates := map[string]interface{}{"status": req.Status}
+	lastTrial := previousMonth(currentMonth())
+	if isEndDateAfterStartDate(sub.StartDate, lastTrial) {
+		updates["trial_end"] = lastTrial
+	} else {
+		updates["trial_end"] = nil
+	}
+
+	if err := s.repo.Update(ctx, id, updates); err != nil {
+		return nil, err
+	}
+	return s.updated(ctx, id)
+}
+
+// Cancel ends billing after the effective month (the current one by default)
+// but, unlike Delete, keeps the subscription in listings and totals for the
+// months it was paid.
+func (s *subscriptionService) Cancel(ctx context.Context, id string, req *model.CancelSubscriptionRequest) (*model.Subscription, error) {
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err := s.authorize(ctx, authz.ActionWrite, sub.UserID); err != nil {
+		return nil, err
+	}
+	if !canTransition(sub.Status, model.SubscriptionStatusCancelled) {
+		return nil, transitionError(sub.Status, model.SubscriptionStatusCancelled)
+	}
+
+	month := currentMonth()
+	if req.EffectiveMonth != nil {
+		month = *req.EffectiveMonth
+		if !isValidDateFormat(month) {
+			return nil, &ValidationError{Field: "effective_month", Message: "invalid effective_month format, expected MM-YYYY"}
+		}
+		if !isEndDateAfterStartDate(sub.StartDate, month) {
+			return nil, &ValidationError{Field: "effective_month", Message: "effective_month must be after or equal to start_date"}
+		}
+	} else if !isEndDateAfterStartDate(sub.StartDate, month) {
+		month = sub.StartDate
+	}
+	if sub.EndDate != nil && !isEndDateAfterStartDate(month, *sub.EndDate) {
+		return nil, &ValidationError{Field: "effective_month", Message: "effective_month must be before or equal to end_date"}
+	}
+
+	updates := map[string]interface{}{
+		"status":       model.SubscriptionStatusCancelled,
+		"end_date":     month,
+		"cancelled_at": time.Now(),
+	}
+	if req.Reason != nil {
+		updates["cancellation_reason"] = *req.Reason
+	}
+
+	if err := s.repo.Update(ctx, id, updates); err != nil {
+		return nil, err
+	}
+	return s.updated(ctx, id)
+}
+
+// UpcomingRenewals lists the monthly charges of a user's subscriptions that
+// fall within the next withinDays days, in chronological order. Months
+// outside the subscription period, trial months and paused months are not
+// charged; billing days past the end of a short month fall on its last day.
+func (s *subscriptionService) UpcomingRenewals(ctx context.Context, userID string, withinDays int) ([]model.Renewal, error) {
+	if withinDays < 1 || withinDays > 366 {
+		return nil, &ValidationError{Field: "within_days", Message: "within_days must be between 1 and 366"}
+	}
+
+	if err := s.authorize(ctx, authz.ActionRead, userID); err != nil {
+		return nil, err
+	}
+
+	subscriptions, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	today := today()
+	return s.renewalsBetween(ctx, subscriptions, today, today.AddDate(0, 0, withinDays))
+}
+
+// DueRenewals lists the charges of all subscriptions falling exactly
+// daysAhead days from today.
+func (s *subscriptionService) DueRenewals(ctx context.Context, daysAhead int) ([]model.Renewal, error) {
+	if err := s.authorize(ctx, authz.ActionRead, ""); err != nil {
+		return nil, err
+	}
+
+	subscriptions, err := s.repo.ListBillable(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	day := today().AddDate(0, 0, daysAhead)
+	return s.renewalsBetween(ctx, subscriptions, day, day)
+}
+
+func (s *subscriptionService) renewalsBetween(ctx context.Context, subscriptions []model.Subscription, from, until time.Time) ([]model.Renewal, error) {
+	ids := make([]string, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		if sub.Status != model.SubscriptionStatusExpired {
+			ids = append(ids, sub.ID)
+		}
+	}
+	// One query for every subscription's pauses, not one each.
+	var pausesBySubscription map[string][]model.SubscriptionPause
+	if len(ids) > 0 {
+		var err error
+		pausesBySubscription, err = s.repo.ListPausesFor(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	renewals := make([]model.Renewal, 0)
+	for _, sub := range subscriptions {
+		if sub.Status == model.SubscriptionStatusExpired {
+			continue
+		}
+
+		pauses := pausesBySubscription[sub.ID]
+		start, _ := time.Parse("01-2006", sub.StartDate)
+		for month := firstOfMonth(from); !month.After(until); month = month.AddDate(0, 1, 0) {
+			if month.Before(start) || !isBillableMonth(sub, pauses, month) {
+				continue
+			}
+
+			chargeDate := month.AddDate(0, 0, min(sub.BillingDay, daysInMonth(month))-1)
+			if chargeDate.Before(from) || chargeDate.After(until) {
+				continue
+			}
+
+			renewals = append(renewals, model.Renewal{
+				SubscriptionID: sub.ID,
+				TenantID:       sub.TenantID,
+				UserID:         sub.UserID,
+				ServiceName:    sub.ServiceName,
+				Amount:         sub.Price,
+				ChargeDate:     chargeDate.Format("2006-01-02"),
+			})
+		}
+	}
+
+	sort.SliceStable(renewals, func(i, j int) bool {
+		if renewals[i].ChargeDate != renewals[j].ChargeDate {
+			return renewals[i].ChargeDate < renewals[j].ChargeDate
+		}
+		return renewals[i].ServiceName < renewals[j].ServiceName
+	})
+
+	return renewals, nil
+}
+
+func isBillableMonth(sub model.Subscription, pauses []model.SubscriptionPause, month time.Time) bool {
+	if sub.EndDate != nil {
+		end, _ := time.Parse("01-2006", *sub.EndDate)
+		if month.After(end) {
+			return false
+		}
+	}
+	if sub.TrialEnd != nil {
+		trialEnd, _ := time.Parse("01-2006", *sub.TrialEnd)
+		if !month.After(trialEnd) {
+			return false
+		}
+	}
+	for _, pause := range pauses {
+		pauseStart, _ := time.Parse("01-2006", pause.StartMonth)
+		if month.Before(pauseStart) {
+			continue
+		}
+		if pause.EndMonth == nil {
+			return false
+		}
+		pauseEnd, _ := time.Parse("01-2006", *pause.EndMonth)
+		if !month.After(pauseEnd) {
+			return false
+		}
+	}
+	return true
+}
+
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func firstOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func daysInMonth(month time.Time) int {
+	return firstOfMonth(month).AddDate(0, 1, -1).Day()
+}
+
+func (s *subscriptionService) ListOverlaps(ctx context.Context, userID string) ([]model.SubscriptionOverlap, error) {
+	userID, err := s.readableUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListOverlaps(ctx, userID)
+}
+
+func (s *subscriptionService) ListMembers(ctx context.Context, id string) ([]model.SubscriptionMember, error) {
+	sub, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	return s.repo.ListMembers(ctx, id)
+}
+
+// SetMember shares the subscription with userID or changes their share.
+// The members' shares together may not exceed the price; the owner pays
+// the rest.
+func (s *subscriptionService) SetMember(ctx context.Context, id, userID string, req *model.SetMemberRequest) (*model.SubscriptionMember, error) {
+	if req.ShareType == model.ShareTypePercent && *req.ShareValue > 100 {
+		return nil, &ValidationError{Field: "share_value", Message: "percent share must not exceed 100"}
+	}
+
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err := s.authorize(ctx, authz.ActionWrite, sub.UserID); err != nil {
+		return nil, err
+	}
+	if sub.UserID == userID {
+		return nil, &ValidationError{Field: "user_id", Message: "owner cannot be a member of their own subscription"}
+	}
+
+	members, err := s.repo.ListMembers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	member := &model.SubscriptionMember{
+		SubscriptionID: id,
+		UserID:         userID,
+		ShareType:      req.ShareType,
+		ShareValue:     *req.ShareValue,
+	}
+	shares := []model.SubscriptionMember{*member}
+	for _, other := range members {
+		if other.UserID != userID {
+			shares = append(shares, other)
+		}
+	}
+	if membersShare(shares, sub.Price) > sub.Price {
+		return nil, &ValidationError{Field: "share_value", Message: "members' shares exceed the subscription price"}
+	}
+
+	if err := s.repo.SetMember(ctx, member); err != nil {
+		return nil, err
+	}
+	if _, err := s.updated(ctx, id); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// membersShare is what members pay of price in total, counting fixed
+// shares in full even where they exceed the price.
+func membersShare(members []model.SubscriptionMember, price int) int {
+	total := 0
+	for _, member := range members {
+		if member.ShareType == model.ShareTypeFixed {
+			total += member.ShareValue
+		} else {
+			total += member.Amount(price)
+		}
+	}
+	return total
+}
+
+func (s *subscriptionService) RemoveMember(ctx context.Context, id, userID string) error {
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if sub == nil {
+		return ErrSubscriptionNotFound
+	}
+	// Members may leave a subscription without the owner's rights.
+	if err := s.authorize(ctx, authz.ActionWrite, sub.UserID); err != nil {
+		if principal := authz.PrincipalFrom(ctx); principal == nil || principal.Subject != userID {
+			return err
+		}
+	}
+
+	members, err := s.repo.ListMembers(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		if member.UserID == userID {
+			if err := s.repo.DeleteMember(ctx, id, userID); err != nil {
+				return err
+			}
+			_, err := s.updated(ctx, id)
+			return err
+		}
+	}
+	return ErrMemberNotFound
+}
+
+// authorize checks action on ownerID's subscriptions with the configured
+// authorizer.
+func (s *subscriptionService) authorize(ctx context.Context, action, ownerID string) error {
+	if s.authz == nil {
+		return nil
+	}
+	return s.authz.Authorize(ctx, action, ownerID)
+}
+
+// authorizeRead lets the owner's readers and the subscription's members see
+// it.
+func (s *subscriptionService) authorizeRead(ctx context.Context, sub *model.Subscription) error {
+	err := s.authorize(ctx, authz.ActionRead, sub.UserID)
+	if err == nil || !errors.Is(err, authz.ErrForbidden) {
+		return err
+	}
+
+	principal := authz.PrincipalFrom(ctx)
+	if principal == nil {
+		return err
+	}
+	members, listErr := s.repo.ListMembers(ctx, sub.ID)
+	if listErr != nil {
+		return listErr
+	}
+	for _, member := range members {
+		if member.UserID == principal.Subject {
+			return nil
+		}
+	}
+	return err
+}
+
+// readableUser narrows a query by user to what the caller may read: any
+// user, including all of them, for those who may read everyone's data, and
+// otherwise only the caller.
+func (s *subscriptionService) readableUser(ctx context.Context, userID string) (string, error) {
+	if s.authorize(ctx, authz.ActionRead, "") == nil {
+		return userID, nil
+	}
+
+	principal := authz.PrincipalFrom(ctx)
+	if principal == nil || (userID != "" && userID != principal.Subject) {
+		return "", authz.ErrForbidden
+	}
+	if err := s.authorize(ctx, authz.ActionRead, principal.Subject); err != nil {
+		return "", err
+	}
+	return principal.Subject, nil
+}
+
+// updated reloads a changed subscription and announces the change.
+func (s *subscriptionService) updated(ctx context.Context, id string) (*model.Subscription, error) {
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub != nil {
+		s.publish(ctx, model.EventSubscriptionUpdated, sub)
+	}
+	return sub, nil
+}
+
+// publish hands an event to the publisher. A failure is logged rather than
+// returned, as the change itself has already been stored.
+func (s *subscriptionService) publish(ctx context.Context, eventType string, data interface{}) {
+	if s.events == nil {
+		return
+	}
+	if err := s.events.Publish(ctx, eventType, data); err != nil {
+		logging.FromContext(ctx).Error("failed to publish event", "event_type", eventType, "error", err)
+	}
+}
+
+// userReferenceError reports a subscription for a user that does not exist
+// as a validation error.
+func userReferenceError(err error) error {
+	if errors.Is(err, repository.ErrMissingReference) {
+		return &ValidationError{Field: "user_id", Message: "user does not exist"}
+	}
+	return err
+}
+
+func canTransition(from, to string) bool {
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func transitionError(from, to string) error {
+	return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
 }
 
 func isValidDateFormat(date string) bool {
@@ -145,3 +1032,12 @@ func isEndDateAfterStartDate(start, end string) bool {
 	endTime, _ := time.Parse("01-2006", end)
 	return !endTime.Before(startTime)
 }
+
+func currentMonth() string {
+	return time.Now().Format("01-2006")
+}
+
+func previousMonth(month string) string {
+	t, _ := time.Parse("01-2006", month)
+	return t.AddDate(0, -1, 0).Format("01-2006")
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+)
+
+const tracerName = "github.com/t5129001t-jpg/subscription-service/internal/service"
+
+type tracedSubscriptionService struct {
+	next SubscriptionService
+}
+
+// NewTracedSubscriptionService wraps next so that each method call is a
+// span, marked as failed when the method returns an error.
+func NewTracedSubscriptionService(next SubscriptionService) SubscriptionService {
+	return &tracedSubscriptionService{next: next}
+}
+
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "SubscriptionService."+method, trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func subscriptionID(id string) attribute.KeyValue {
+	return attribute.String("subscription.id", id)
+}
+
+// filterAttributes describe which filters a query combines, without their
+// values, so that slow combinations can be found across traces.
+func filterAttributes(filter model.SubscriptionFilter) []attribute.KeyValue {
+	var used []string
+	for name, value := range map[string]string{
+		"user_id":      filter.UserID,
+		"service_name": filter.ServiceName,
+		"month":        filter.Month,
+		"start_month":  filter.StartMonth,
+		"end_month":    filter.EndMonth,
+		"status":       filter.Status,
+	} {
+		if value != "" {
+			used = append(used, name)
+		}
+	}
+	sort.Strings(used)
+
+	return []attribute.KeyValue{
+		attribute.String("subscription.filter", strings.Join(used, ",")),
+		attribute.String("subscription.filter.status", filter.Status),
+		attribute.Int("subscription.filter.limit", filter.Limit),
+		attribute.Int("subscription.filter.offset", filter.Offset),
+	}
+}
+
+func (s *tracedSubscriptionService) Create(ctx context.Context, req *model.CreateSubscriptionRequest) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "Create")
+	sub, err := s.next.Create(ctx, req)
+	if sub != nil {
+		span.SetAttributes(subscriptionID(sub.ID))
+	}
+	endSpan(span, err)
+	return sub, err
+}
+
+func (s *tracedSubscriptionService) GetByID(ctx context.Context, id string) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "GetByID", subscriptionID(id))
+	sub, err := s.next.GetByID(ctx, id)
+	endSpan(span, err)
+	return sub, err
+}
+
+func (s *tracedSubscriptionService) Update(ctx context.Context, id string, req *model.UpdateSubscriptionRequest) (*model.BudgetWarning, error) {
+	ctx, span := startSpan(ctx, "Update", subscriptionID(id))
+	warning, err := s.next.Update(ctx, id, req)
+	endSpan(span, err)
+	return warning, err
+}
+
+func (s *tracedSubscriptionService) Delete(ctx context.Context, id string) error {
+	ctx, span := startSpan(ctx, "Delete", subscriptionID(id))
+	err := s.next.Delete(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedSubscriptionService) Restore(ctx context.Context, id string) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "Restore", subscriptionID(id))
+	sub, err := s.next.Restore(ctx, id)
+	endSpan(span, err)
+	return sub, err
+}
+
+func (s *tracedSubscriptionService) List(ctx context.Context, filter model.SubscriptionFilter) ([]model.Subscription, int, error) {
+	ctx, span := startSpan(ctx, "List", filterAttributes(filter)...)
+	subscriptions, total, err := s.next.List(ctx, filter)
+	span.SetAttributes(attribute.Int("subscription.total", total))
+	endSpan(span, err)
+	return subscriptions, total, err
+}
+
+func (s *tracedSubscriptionService) GetTotalPrice(ctx context.Context, filter model.SubscriptionFilter) (int, error) {
+	ctx, span := startSpan(ctx, "GetTotalPrice", filterAttributes(filter)...)
+	total, err := s.next.GetTotalPrice(ctx, filter)
+	endSpan(span, err)
+	return total, err
+}
+
+func (s *tracedSubscriptionService) Pause(ctx context.Context, id string, req *model.PauseSubscriptionRequest) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "Pause", subscriptionID(id))
+	sub, err := s.next.Pause(ctx, id, req)
+	endSpan(span, err)
+	return sub, err
+}
+
+func (s *tracedSubscriptionService) Resume(ctx context.Context, id string, req *model.ResumeSubscriptionRequest) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "Resume", subscriptionID(id))
+	sub, err := s.next.Resume(ctx, id, req)
+	endSpan(span, err)
+	return sub, err
+}
+
+func (s *tracedSubscriptionService) ChangeStatus(ctx context.Context, id string, req *model.ChangeStatusRequest) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "ChangeStatus", subscriptionID(id))
+	sub, err := s.next.ChangeStatus(ctx, id, req)
+	endSpan(span, err)
+	return sub, err
+}
+
+func (s *tracedSubscriptionService) Cancel(ctx context.Context, id string, req *model.CancelSubscriptionRequest) (*model.Subscription, error) {
+	ctx, span := startSpan(ctx, "Cancel", subscriptionID(id))
+	sub, err := s.next.Cancel(ctx, id, req)
+	endSpan(span, err)
+	return sub, err
+}
+
+func (s *tracedSubscriptionService) UpcomingRenewals(ctx context.Context, userID string, withinDays int) ([]model.Renewal, error) {
+	ctx, span := startSpan(ctx, "UpcomingRenewals", attribute.Int("renewal.within_days", withinDays))
+	renewals, err := s.next.UpcomingRenewals(ctx, userID, withinDays)
+	endSpan(span, err)
+	return renewals, err
+}
+
+func (s *tracedSubscriptionService) DueRenewals(ctx context.Context, daysAhead int) ([]model.Renewal, error) {
+	ctx, span := startSpan(ctx, "DueRenewals", attribute.Int("renewal.days_ahead", daysAhead))
+	renewals, err := s.next.DueRenewals(ctx, daysAhead)
+	endSpan(span, err)
+	return renewals, err
+}
+
+func (s *tracedSubscriptionService) ListOverlaps(ctx context.Context, userID string) ([]model.SubscriptionOverlap, error) {
+	ctx, span := startSpan(ctx, "ListOverlaps")
+	overlaps, err := s.next.ListOverlaps(ctx, userID)
+	endSpan(span, err)
+	return overlaps, err
+}
+
+func (s *tracedSubscriptionService) ListMembers(ctx context.Context, id string) ([]model.SubscriptionMember, error) {
+	ctx, span := startSpan(ctx, "ListMembers", subscriptionID(id))
+	members, err := s.next.ListMembers(ctx, id)
+	endSpan(span, err)
+	return members, err
+}
+
+func (s *tracedSubscriptionService) SetMember(ctx context.Context, id, userID string, req *model.SetMemberRequest) (*model.SubscriptionMember, error) {
+	ctx, span := startSpan(ctx, "SetMember", subscriptionID(id))
+	member, err := s.next.SetMember(ctx, id, userID, req)
+	endSpan(span, err)
+	return member, err
+}
+
+func (s *tracedSubscriptionService) RemoveMember(ctx context.Context, id, userID string) error {
+	ctx, span := startSpan(ctx, "RemoveMember", subscriptionID(id))
+	err := s.next.RemoveMember(ctx, id, userID)
+	endSpan(span, err)
+	return err
+}
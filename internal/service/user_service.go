@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+)
+
+type UserService interface {
+	Create(ctx context.Context, req *model.CreateUserRequest) (*model.User, error)
+	GetByID(ctx context.Context, id string) (*model.User, error)
+	Update(ctx context.Context, id string, req *model.UpdateUserRequest) (*model.User, error)
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, limit, offset int) ([]model.User, int, error)
+	GetSummary(ctx context.Context, id string) (*model.UserSummary, error)
+	Export(ctx context.Context, id string) (*model.UserExport, error)
+	EraseData(ctx context.Context, id, mode string) (*model.ErasureReceipt, error)
+}
+
+var (
+	ErrUserNotFound         = errors.New("user not found")
+	ErrUserExists           = errors.New("user with this id or email already exists")
+	ErrUserHasSubscriptions = errors.New("user still has subscriptions")
+)
+
+type userService struct {
+	repo repository.UserRepository
+	data repository.UserDataRepository
+}
+
+func NewUserService(repo repository.UserRepository, data repository.UserDataRepository) UserService {
+	return &userService{repo: repo, data: data}
+}
+
+func (s *userService) Create(ctx context.Context, req *model.CreateUserRequest) (*model.User, error) {
+	user := &model.User{
+		Name:  req.Name,
+		Email: req.Email,
+	}
+	if req.ID != nil {
+		user.ID = *req.ID
+	}
+
+	if err := s.repo.Create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrUserExists
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *userService) GetByID(ctx context.Context, id string) (*model.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *userService) Update(ctx context.Context, id string, req *model.UpdateUserRequest) (*model.User, error) {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Email != nil {
+		updates["email"] = *req.Email
+	}
+
+	if err := s.repo.Update(ctx, id, updates); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrUserExists
+		}
+		return nil, err
+	}
+	return s.GetByID(ctx, id)
+}
+
+func (s *userService) Delete(ctx context.Context, id string) error {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	err := s.repo.Delete(ctx, id)
+	if errors.Is(err, repository.ErrReferenced) {
+		return ErrUserHasSubscriptions
+	}
+	return err
+}
+
+func (s *userService) List(ctx context.Context, limit, offset int) ([]model.User, int, error) {
+	limit, offset = Page(limit, offset)
+	return s.repo.List(ctx, limit, offset)
+}
+
+func (s *userService) GetSummary(ctx context.Context, id string) (*model.UserSummary, error) {
+	summary, err := s.repo.GetSummary(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if summary == nil {
+		return nil, ErrUserNotFound
+	}
+	return summary, nil
+}
+
+func (s *userService) Export(ctx context.Context, id string) (*model.UserExport, error) {
+	export, err := s.data.Export(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return nil, ErrUserNotFound
+	}
+	return export, nil
+}
+
+// EraseData deletes or anonymises everything stored about the user in the
+// tenant found in ctx, as chosen by mode, which defaults to delete.
+func (s *userService) EraseData(ctx context.Context, id, mode string) (*model.ErasureReceipt, error) {
+	if mode == "" {
+		mode = model.ErasureModeDelete
+	}
+	if mode != model.ErasureModeDelete && mode != model.ErasureModeAnonymise {
+		return nil, &ValidationError{Field: "mode", Message: "mode must be delete or anonymise"}
+	}
+
+	receipt, err := s.data.Erase(ctx, id, mode)
+	if err != nil {
+		return nil, err
+	}
+	if receipt == nil {
+		return nil, ErrUserNotFound
+	}
+	return receipt, nil
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/t5129001t-jpg/subscription-service/internal/authz"
+	"github.com/t5129001t-jpg/subscription-service/internal/config"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
+)
+
+// leaseMargin is added to a batch's lease on top of the time its requests
+// may take, for the database round trips in between.
+const leaseMargin = 30 * time.Second
+
+// WebhookDispatcher delivers queued webhook events and periodically queues
+// renewal_due events for upcoming charges.
+type WebhookDispatcher struct {
+	repo          repository.WebhookRepository
+	webhooks      WebhookService
+	subscriptions SubscriptionService
+	client        *http.Client
+	cfg           config.WebhookConfig
+}
+
+func NewWebhookDispatcher(
+	repo repository.WebhookRepository,
+	webhooks WebhookService,
+	subscriptions SubscriptionService,
+	cfg config.WebhookConfig,
+) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		repo:          repo,
+		webhooks:      webhooks,
+		subscriptions: subscriptions,
+		client:        newWebhookClient(cfg.RequestTimeout, cfg.AllowPrivateTargets),
+		cfg:           cfg,
+	}
+}
+
+// Run dispatches until ctx is cancelled.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	poll := time.NewTicker(d.cfg.PollInterval)
+	defer poll.Stop()
+	scan := time.NewTicker(d.cfg.RenewalScanInterval)
+	defer scan.Stop()
+
+	d.scanRenewals(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			if _, err := d.DispatchPending(ctx); err != nil {
+				slog.Error("failed to dispatch webhooks", "error", err)
+			}
+		case <-scan.C:
+			d.scanRenewals(ctx)
+		}
+	}
+}
+
+// DispatchPending sends one batch of due deliveries and returns how many of
+// them were accepted by their endpoints.
+func (d *WebhookDispatcher) DispatchPending(ctx context.Context) (int, error) {
+	// Deliveries are sent for every tenant at once.
+	ctx = tenant.AllTenants(ctx)
+	// The batch is sent one request at a time, so the lease must outlast
+	// every request timing out.
+	lease := time.Duration(d.cfg.BatchSize)*d.cfg.RequestTimeout + leaseMargin
+	deliveries, err := d.repo.ClaimDueDeliveries(ctx, d.cfg.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			break
+		}
+
+		// Skip deliveries another dispatcher has claimed since, rather
+		// than sending them twice.
+		held, err := d.repo.HoldsLease(ctx, delivery.ID, delivery.LeaseToken)
+		if err != nil {
+			return delivered, err
+		}
+		if !held {
+			continue
+		}
+
+		attempts := delivery.Attempts + 1
+		sendErr := d.send(ctx, delivery)
+
+		switch {
+		case sendErr == nil:
+			err = d.repo.MarkDelivered(ctx, delivery.ID, delivery.LeaseToken, attempts)
+			delivered++
+		case attempts >= d.cfg.MaxAttempts:
+			err = d.repo.MarkFailed(ctx, delivery.ID, delivery.LeaseToken, attempts, sendErr.Error())
+		default:
+			next := time.Now().Add(d.backoff(attempts))
+			err = d.repo.MarkRetry(ctx, delivery.ID, delivery.LeaseToken, attempts, next, sendErr.Error())
+		}
+		if err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+func (d *WebhookDispatcher) send(ctx context.Context, delivery model.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Id", delivery.ID)
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", SignWebhookPayload(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// backoff doubles the delay after every failed attempt, up to RetryMaxDelay.
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.RetryBaseDelay
+	for i := 1; i < attempts && delay < d.cfg.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.RetryMaxDelay)
+}
+
+func (d *WebhookDispatcher) scanRenewals(ctx context.Context) {
+	// Renewals are scanned for every tenant at once.
+	ctx = tenant.AllTenants(authz.SystemContext(ctx))
+	renewals, err := d.subscriptions.DueRenewals(ctx, d.cfg.RenewalDaysAhead)
+	if err != nil {
+		slog.Error("failed to list due renewals", "error", err)
+		return
+	}
+	if err := d.webhooks.PublishRenewalsDue(ctx, renewals, d.cfg.RenewalDaysAhead); err != nil {
+		slog.Error("failed to queue renewal webhooks", "error", err)
+	}
+}
+
+// SignWebhookPayload returns the X-Webhook-Signature value for a delivery:
+// the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/t5129001t-jpg/subscription-service/internal/config"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
+)
+
+type WebhookService interface {
+	EventPublisher
+	Create(ctx context.Context, req *model.CreateWebhookRequest) (*model.Webhook, error)
+	GetByID(ctx context.Context, id string) (*model.Webhook, error)
+	List(ctx context.Context) ([]model.Webhook, error)
+	Delete(ctx context.Context, id string) error
+	ListDeliveries(ctx context.Context, id string, limit int) ([]model.WebhookDelivery, error)
+	PublishRenewalsDue(ctx context.Context, renewals []model.Renewal, daysAhead int) error
+}
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+type webhookService struct {
+	repo repository.WebhookRepository
+	cfg  config.WebhookConfig
+}
+
+func NewWebhookService(repo repository.WebhookRepository, cfg config.WebhookConfig) WebhookService {
+	return &webhookService{repo: repo, cfg: cfg}
+}
+
+// Create registers a webhook for the tenant in ctx, which receives only
+// that tenant's events. The URL must be https and, unless the config
+// allows private targets, resolve to public addresses only. The signing
+// secret is generated when the client does not supply one and is only
+// returned here.
+func (s *webhookService) Create(ctx context.Context, req *model.CreateWebhookRequest) (*model.Webhook, error) {
+	if err := validateWebhookURL(ctx, req.URL, s.cfg.AllowPrivateTargets); err != nil {
+		return nil, err
+	}
+
+	secret := ""
+	if req.Secret != nil {
+		secret = *req.Secret
+	} else {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	webhook := &model.Webhook{
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+	}
+
+	if err := s.repo.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (s *webhookService) GetByID(ctx context.Context, id string) (*model.Webhook, error) {
+	webhook, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+func (s *webhookService) List(ctx context.Context) ([]model.Webhook, error) {
+	webhooks, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+func (s *webhookService) Delete(ctx context.Context, id string) error {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, id string, limit int) ([]model.WebhookDelivery, error) {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	return s.repo.ListDeliveries(ctx, id, limit)
+}
+
+// Publish queues the event for every webhook of the tenant in ctx
+// subscribed to its type. The dispatcher delivers queued events in the
+// background.
+func (s *webhookService) Publish(ctx context.Context, eventType string, data interface{}) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return repository.ErrNoTenant
+	}
+	return s.enqueue(ctx, tenantID, eventType, data, "")
+}
+
+// PublishRenewalsDue queues a renewal_due event per charge for the webhooks
+// of the charge's tenant. Events are keyed by subscription and charge date,
+// so repeated scans of the same day queue each of them once.
+func (s *webhookService) PublishRenewalsDue(ctx context.Context, renewals []model.Renewal, daysAhead int) error {
+	for _, renewal := range renewals {
+		event := model.RenewalDueEvent{Renewal: renewal, DaysAhead: daysAhead}
+		key := fmt.Sprintf("%s:%s:%s", model.EventSubscriptionRenewalDue, renewal.SubscriptionID, renewal.ChargeDate)
+		if err := s.enqueue(ctx, renewal.TenantID, model.EventSubscriptionRenewalDue, event, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *webhookService) enqueue(ctx context.Context, tenantID, eventType string, data interface{}, dedupeKey string) error {
+	webhooks, err := s.repo.ListByEventType(ctx, tenantID, eventType)
+	if err != nil {
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(model.WebhookEvent{
+		ID:        uuid.NewString(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      raw,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		delivery := &model.WebhookDelivery{
+			WebhookID: webhook.ID,
+			EventType: eventType,
+			Payload:   payload,
+		}
+		if dedupeKey != "" {
+			key := dedupeKey + ":" + webhook.ID
+			delivery.DedupeKey = &key
+		}
+		if err := s.repo.EnqueueDelivery(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
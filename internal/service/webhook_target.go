@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// errPrivateWebhookTarget refuses a connection to an address webhooks may
+// not reach.
+var errPrivateWebhookTarget = errors.New("webhook target is not a public address")
+
+// isPublicAddress reports whether ip may receive webhooks. Loopback,
+// private, link-local, multicast and unspecified addresses are refused, so
+// that a webhook cannot be pointed at the service's own network.
+func isPublicAddress(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate()
+}
+
+// validateWebhookURL accepts only https URLs whose host resolves to public
+// addresses. With allowPrivate, any http or https URL is accepted.
+func validateWebhookURL(ctx context.Context, raw string, allowPrivate bool) error {
+	target, err := url.Parse(raw)
+	if err != nil || target.Hostname() == "" {
+		return &ValidationError{Field: "url", Message: "url must be an absolute URL"}
+	}
+	if allowPrivate && (target.Scheme == "http" || target.Scheme == "https") {
+		return nil
+	}
+	if target.Scheme != "https" {
+		return &ValidationError{Field: "url", Message: "url must use https"}
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, target.Hostname())
+	if err != nil {
+		return &ValidationError{Field: "url", Message: fmt.Sprintf("url host %s cannot be resolved", target.Hostname())}
+	}
+	for _, addr := range addrs {
+		if !isPublicAddress(addr.IP) {
+			return &ValidationError{Field: "url", Message: "url must not point to a private, loopback or link-local address"}
+		}
+	}
+	return nil
+}
+
+// newWebhookClient returns the client deliveries are sent with. Its dialer
+// checks every address it connects to, after name resolution and on
+// redirects, so a host that later resolves to a private address is refused
+// too. Proxies are not used, as they would hide the real target.
+func newWebhookClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicAddress(ip) {
+				return fmt.Errorf("%w: %s", errPrivateWebhookTarget, host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -1,3 +0,0 @@
-DROP TRIGGER IF EXISTS update_subscriptions_updated_at ON subscriptions;
-DROP FUNCTION IF EXISTS update_updated_at_column();
-DROP TABLE IF EXISTS subscriptions;
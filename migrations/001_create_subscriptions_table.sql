@@ -1,3 +1,4 @@
+-- +goose Up
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
 CREATE TABLE IF NOT EXISTS subscriptions (
@@ -19,6 +20,7 @@ CREATE INDEX idx_subscriptions_user_id ON subscriptions(user_id) WHERE deleted_a
 CREATE INDEX idx_subscriptions_service_name ON subscriptions(service_name) WHERE deleted_at IS NULL;
 CREATE INDEX idx_subscriptions_dates ON subscriptions(start_date, end_date) WHERE deleted_at IS NULL;
 
+-- +goose StatementBegin
 CREATE OR REPLACE FUNCTION update_updated_at_column()
 RETURNS TRIGGER AS $$
 BEGIN
@@ -26,8 +28,14 @@ BEGIN
     RETURN NEW;
 END;
 $$ language 'plpgsql';
+-- +goose StatementEnd
 
 CREATE TRIGGER update_subscriptions_updated_at
     BEFORE UPDATE ON subscriptions
     FOR EACH ROW
     EXECUTE FUNCTION update_updated_at_column();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS update_subscriptions_updated_at ON subscriptions;
+DROP FUNCTION IF EXISTS update_updated_at_column();
+DROP TABLE IF EXISTS subscriptions;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS subscription_pauses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    start_month VARCHAR(7) NOT NULL,
+    end_month VARCHAR(7),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT valid_start_month CHECK (start_month ~ '^(0[1-9]|1[0-2])-[0-9]{4}$'),
+    CONSTRAINT valid_end_month CHECK (end_month IS NULL OR end_month ~ '^(0[1-9]|1[0-2])-[0-9]{4}$')
+);
+
+CREATE INDEX idx_subscription_pauses_subscription_id ON subscription_pauses(subscription_id);
+CREATE UNIQUE INDEX idx_subscription_pauses_open ON subscription_pauses(subscription_id) WHERE end_month IS NULL;
+
+CREATE TRIGGER update_subscription_pauses_updated_at
+    BEFORE UPDATE ON subscription_pauses
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS update_subscription_pauses_updated_at ON subscription_pauses;
+DROP TABLE IF EXISTS subscription_pauses;
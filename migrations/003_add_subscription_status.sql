@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE subscriptions
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active',
+    ADD COLUMN trial_end VARCHAR(7),
+    ADD CONSTRAINT valid_status CHECK (status IN ('trial', 'active', 'cancelled', 'expired')),
+    ADD CONSTRAINT valid_trial_end CHECK (trial_end IS NULL OR trial_end ~ '^(0[1-9]|1[0-2])-[0-9]{4}$');
+
+CREATE INDEX idx_subscriptions_status ON subscriptions(status) WHERE deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_subscriptions_status;
+
+ALTER TABLE subscriptions
+    DROP CONSTRAINT IF EXISTS valid_trial_end,
+    DROP CONSTRAINT IF EXISTS valid_status,
+    DROP COLUMN IF EXISTS trial_end,
+    DROP COLUMN IF EXISTS status;
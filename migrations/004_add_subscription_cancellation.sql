@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE subscriptions
+    ADD COLUMN cancellation_reason TEXT,
+    ADD COLUMN cancelled_at TIMESTAMP WITH TIME ZONE;
+
+-- +goose Down
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS cancellation_reason;
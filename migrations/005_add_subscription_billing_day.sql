@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE subscriptions
+    ADD COLUMN billing_day SMALLINT NOT NULL DEFAULT 1,
+    ADD CONSTRAINT valid_billing_day CHECK (billing_day BETWEEN 1 AND 31);
+
+-- +goose Down
+ALTER TABLE subscriptions
+    DROP CONSTRAINT IF EXISTS valid_billing_day,
+    DROP COLUMN IF EXISTS billing_day;
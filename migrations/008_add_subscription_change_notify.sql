@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_subscription_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    event TEXT;
+    row subscriptions%ROWTYPE;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        event := 'subscription.created';
+        row := NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        event := 'subscription.deleted';
+        row := OLD;
+    ELSIF NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN
+        event := 'subscription.deleted';
+        row := NEW;
+    ELSE
+        event := 'subscription.updated';
+        row := NEW;
+    END IF;
+
+    PERFORM pg_notify('subscription_changes', json_build_object(
+        'event', event,
+        'id', row.id,
+        'user_id', row.user_id,
+        'occurred_at', NOW()
+    )::text);
+
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+-- +goose StatementEnd
+
+CREATE TRIGGER notify_subscriptions_change
+    AFTER INSERT OR UPDATE OR DELETE ON subscriptions
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_subscription_change();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS notify_subscriptions_change ON subscriptions;
+DROP FUNCTION IF EXISTS notify_subscription_change();
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS budgets (
+    user_id UUID PRIMARY KEY,
+    monthly_limit INTEGER NOT NULL CHECK (monthly_limit >= 0),
+    enforcement VARCHAR(8) NOT NULL DEFAULT 'warn',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT valid_enforcement CHECK (enforcement IN ('warn', 'reject'))
+);
+
+CREATE TRIGGER update_budgets_updated_at
+    BEFORE UPDATE ON budgets
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS update_budgets_updated_at ON budgets;
+DROP TABLE IF EXISTS budgets;
@@ -0,0 +1,51 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS subscription_members (
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    share_type VARCHAR(8) NOT NULL,
+    share_value INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (subscription_id, user_id),
+    CONSTRAINT valid_share_type CHECK (share_type IN ('percent', 'fixed')),
+    CONSTRAINT valid_share_value CHECK (
+        share_value >= 0 AND (share_type <> 'percent' OR share_value <= 100)
+    )
+);
+
+CREATE INDEX idx_subscription_members_user_id ON subscription_members(user_id);
+
+CREATE TRIGGER update_subscription_members_updated_at
+    BEFORE UPDATE ON subscription_members
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- What each user pays for a subscription: members pay their share, the
+-- owner pays whatever the members do not cover.
+CREATE OR REPLACE VIEW subscription_shares AS
+WITH member_amounts AS (
+    SELECT
+        m.subscription_id,
+        m.user_id,
+        CASE m.share_type
+            WHEN 'percent' THEN s.price * m.share_value / 100
+            ELSE LEAST(m.share_value, s.price)
+        END AS amount
+    FROM subscription_members m
+    JOIN subscriptions s ON s.id = m.subscription_id
+)
+SELECT
+    s.id AS subscription_id,
+    s.user_id,
+    s.price - COALESCE((
+        SELECT SUM(ma.amount) FROM member_amounts ma WHERE ma.subscription_id = s.id
+    ), 0) AS amount
+FROM subscriptions s
+UNION ALL
+SELECT subscription_id, user_id, amount FROM member_amounts;
+
+-- +goose Down
+DROP VIEW IF EXISTS subscription_shares;
+DROP TRIGGER IF EXISTS update_subscription_members_updated_at ON subscription_members;
+DROP TABLE IF EXISTS subscription_members;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Proof that a user's data was erased. The user is identified only by a
+-- SHA-256 hash of their id so that the receipt itself holds no personal data.
+CREATE TABLE IF NOT EXISTS erasure_receipts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subject_hash VARCHAR(64) NOT NULL,
+    mode VARCHAR(16) NOT NULL,
+    counts JSONB NOT NULL,
+    erased_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT valid_erasure_mode CHECK (mode IN ('delete', 'anonymise'))
+);
+
+CREATE INDEX idx_erasure_receipts_subject_hash ON erasure_receipts(subject_hash);
+
+-- +goose Down
+DROP TABLE IF EXISTS erasure_receipts;
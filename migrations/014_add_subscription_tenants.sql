@@ -0,0 +1,116 @@
+-- +goose Up
+-- Rows are visible only to transactions that set app.tenant_id to their
+-- tenant, or app.all_tenants to 'on' for work across tenants. Transactions
+-- setting neither see nothing.
+ALTER TABLE subscriptions
+    ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' CHECK (tenant_id <> '');
+
+ALTER TABLE subscriptions
+    ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id', true);
+
+CREATE INDEX idx_subscriptions_tenant_user ON subscriptions(tenant_id, user_id);
+
+ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON subscriptions
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.all_tenants', true) = 'on'
+    );
+
+-- Pauses and members follow the subscription they belong to.
+ALTER TABLE subscription_pauses ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscription_pauses FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON subscription_pauses
+    USING (EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = subscription_id));
+
+ALTER TABLE subscription_members ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscription_members FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON subscription_members
+    USING (EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = subscription_id));
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_subscription_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    event TEXT;
+    row subscriptions%ROWTYPE;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        event := 'subscription.created';
+        row := NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        event := 'subscription.deleted';
+        row := OLD;
+    ELSIF NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN
+        event := 'subscription.deleted';
+        row := NEW;
+    ELSE
+        event := 'subscription.updated';
+        row := NEW;
+    END IF;
+
+    PERFORM pg_notify('subscription_changes', json_build_object(
+        'event', event,
+        'id', row.id,
+        'tenant_id', row.tenant_id,
+        'user_id', row.user_id,
+        'occurred_at', NOW()
+    )::text);
+
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_subscription_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    event TEXT;
+    row subscriptions%ROWTYPE;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        event := 'subscription.created';
+        row := NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        event := 'subscription.deleted';
+        row := OLD;
+    ELSIF NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN
+        event := 'subscription.deleted';
+        row := NEW;
+    ELSE
+        event := 'subscription.updated';
+        row := NEW;
+    END IF;
+
+    PERFORM pg_notify('subscription_changes', json_build_object(
+        'event', event,
+        'id', row.id,
+        'user_id', row.user_id,
+        'occurred_at', NOW()
+    )::text);
+
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+-- +goose StatementEnd
+
+DROP POLICY IF EXISTS tenant_isolation ON subscription_members;
+ALTER TABLE subscription_members NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_members DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON subscription_pauses;
+ALTER TABLE subscription_pauses NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_pauses DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON subscriptions;
+ALTER TABLE subscriptions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS idx_subscriptions_tenant_user;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,43 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_rate_limit_buckets_updated_at ON rate_limit_buckets(updated_at);
+
+-- rate_limit_take refills the bucket for the time since it was last used
+-- and takes a token if a whole one is left. The row lock serialises
+-- concurrent requests of one client across replicas.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION rate_limit_take(bucket_key TEXT, burst INTEGER, per_second DOUBLE PRECISION)
+RETURNS TABLE (allowed BOOLEAN, tokens_left DOUBLE PRECISION) AS $$
+DECLARE
+    available DOUBLE PRECISION;
+BEGIN
+    INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+    VALUES (bucket_key, burst, clock_timestamp())
+    ON CONFLICT (key) DO NOTHING;
+
+    SELECT LEAST(burst, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * per_second)
+    INTO available
+    FROM rate_limit_buckets b
+    WHERE b.key = bucket_key
+    FOR UPDATE;
+
+    allowed := available >= 1;
+    tokens_left := CASE WHEN allowed THEN available - 1 ELSE available END;
+
+    UPDATE rate_limit_buckets b
+    SET tokens = tokens_left, updated_at = clock_timestamp()
+    WHERE b.key = bucket_key;
+
+    RETURN NEXT;
+END;
+$$ language 'plpgsql';
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION IF EXISTS rate_limit_take(TEXT, INTEGER, DOUBLE PRECISION);
+DROP TABLE IF EXISTS rate_limit_buckets;
@@ -0,0 +1,39 @@
+-- +goose Up
+-- Webhooks belong to the tenant that registered them and receive only that
+-- tenant's events. Existing webhooks are kept by the default tenant.
+ALTER TABLE webhooks
+    ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' CHECK (tenant_id <> '');
+
+ALTER TABLE webhooks
+    ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id', true);
+
+CREATE INDEX idx_webhooks_tenant ON webhooks(tenant_id) WHERE deleted_at IS NULL;
+
+ALTER TABLE webhooks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhooks FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON webhooks
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.all_tenants', true) = 'on'
+    );
+
+-- Deliveries follow the webhook they belong to.
+ALTER TABLE webhook_deliveries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhook_deliveries FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON webhook_deliveries
+    USING (EXISTS (SELECT 1 FROM webhooks w WHERE w.id = webhook_id));
+
+-- +goose Down
+DROP POLICY IF EXISTS tenant_isolation ON webhook_deliveries;
+ALTER TABLE webhook_deliveries NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE webhook_deliveries DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON webhooks;
+ALTER TABLE webhooks NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE webhooks DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS idx_webhooks_tenant;
+
+ALTER TABLE webhooks DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- API keys act within the tenant of the admin who issued them. Existing
+-- keys are kept by the default tenant.
+ALTER TABLE api_keys
+    ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' CHECK (tenant_id <> '');
+
+ALTER TABLE api_keys
+    ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id', true);
+
+CREATE INDEX idx_api_keys_tenant ON api_keys(tenant_id, created_at);
+
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON api_keys
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.all_tenants', true) = 'on'
+    );
+
+-- +goose Down
+DROP POLICY IF EXISTS tenant_isolation ON api_keys;
+ALTER TABLE api_keys NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE api_keys DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS idx_api_keys_tenant;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,76 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_subscription_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    event TEXT;
+    row subscriptions%ROWTYPE;
+    member_ids UUID[];
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        event := 'subscription.created';
+        row := NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        event := 'subscription.deleted';
+        row := OLD;
+    ELSIF NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN
+        event := 'subscription.deleted';
+        row := NEW;
+    ELSE
+        event := 'subscription.updated';
+        row := NEW;
+    END IF;
+
+    -- Members see changes of the subscriptions shared with them.
+    SELECT COALESCE(array_agg(m.user_id), '{}') INTO member_ids
+    FROM subscription_members m
+    WHERE m.subscription_id = row.id;
+
+    PERFORM pg_notify('subscription_changes', json_build_object(
+        'event', event,
+        'id', row.id,
+        'tenant_id', row.tenant_id,
+        'user_id', row.user_id,
+        'member_ids', member_ids,
+        'occurred_at', NOW()
+    )::text);
+
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_subscription_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    event TEXT;
+    row subscriptions%ROWTYPE;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        event := 'subscription.created';
+        row := NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        event := 'subscription.deleted';
+        row := OLD;
+    ELSIF NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN
+        event := 'subscription.deleted';
+        row := NEW;
+    ELSE
+        event := 'subscription.updated';
+        row := NEW;
+    END IF;
+
+    PERFORM pg_notify('subscription_changes', json_build_object(
+        'event', event,
+        'id', row.id,
+        'tenant_id', row.tenant_id,
+        'user_id', row.user_id,
+        'occurred_at', NOW()
+    )::text);
+
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+-- +goose StatementEnd
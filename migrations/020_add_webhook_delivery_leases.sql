@@ -0,0 +1,7 @@
+-- +goose Up
+-- Set when a dispatcher claims a delivery, so that it only sends and
+-- records deliveries it still holds.
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS lease_token UUID;
+
+-- +goose Down
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS lease_token;
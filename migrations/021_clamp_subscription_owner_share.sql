@@ -0,0 +1,47 @@
+-- +goose Up
+-- The owner pays what the members do not cover, but never less than
+-- nothing, even if the members' fixed shares add up to more than the price.
+CREATE OR REPLACE VIEW subscription_shares AS
+WITH member_amounts AS (
+    SELECT
+        m.subscription_id,
+        m.user_id,
+        CASE m.share_type
+            WHEN 'percent' THEN s.price * m.share_value / 100
+            ELSE LEAST(m.share_value, s.price)
+        END AS amount
+    FROM subscription_members m
+    JOIN subscriptions s ON s.id = m.subscription_id
+)
+SELECT
+    s.id AS subscription_id,
+    s.user_id,
+    GREATEST(s.price - COALESCE((
+        SELECT SUM(ma.amount) FROM member_amounts ma WHERE ma.subscription_id = s.id
+    ), 0), 0) AS amount
+FROM subscriptions s
+UNION ALL
+SELECT subscription_id, user_id, amount FROM member_amounts;
+
+-- +goose Down
+CREATE OR REPLACE VIEW subscription_shares AS
+WITH member_amounts AS (
+    SELECT
+        m.subscription_id,
+        m.user_id,
+        CASE m.share_type
+            WHEN 'percent' THEN s.price * m.share_value / 100
+            ELSE LEAST(m.share_value, s.price)
+        END AS amount
+    FROM subscription_members m
+    JOIN subscriptions s ON s.id = m.subscription_id
+)
+SELECT
+    s.id AS subscription_id,
+    s.user_id,
+    s.price - COALESCE((
+        SELECT SUM(ma.amount) FROM member_amounts ma WHERE ma.subscription_id = s.id
+    ), 0) AS amount
+FROM subscriptions s
+UNION ALL
+SELECT subscription_id, user_id, amount FROM member_amounts;
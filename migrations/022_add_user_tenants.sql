@@ -0,0 +1,93 @@
+-- +goose Up
+-- Users and budgets belong to a tenant, like the subscriptions they own.
+-- Existing users and budgets are kept by the default tenant and copied, with
+-- the same id, into every other tenant the user owns subscriptions in.
+ALTER TABLE subscriptions DROP CONSTRAINT fk_subscriptions_user;
+ALTER TABLE users DROP CONSTRAINT users_pkey;
+
+ALTER TABLE users
+    ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' CHECK (tenant_id <> '');
+
+INSERT INTO users (tenant_id, id, name, email, created_at, updated_at)
+SELECT s.tenant_id, u.id, u.name, u.email, u.created_at, u.updated_at
+FROM users u
+JOIN (SELECT DISTINCT tenant_id, user_id FROM subscriptions WHERE tenant_id <> 'default') s
+    ON s.user_id = u.id;
+
+ALTER TABLE users ADD PRIMARY KEY (tenant_id, id);
+
+ALTER TABLE users
+    ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id', true);
+
+DROP INDEX idx_users_email;
+CREATE UNIQUE INDEX idx_users_email ON users(tenant_id, lower(email)) WHERE email IS NOT NULL;
+
+-- Foreign keys are checked without row-level security, so the owner must be
+-- matched within the subscription's tenant explicitly.
+ALTER TABLE subscriptions
+    ADD CONSTRAINT fk_subscriptions_user
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users(tenant_id, id) ON DELETE RESTRICT;
+
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON users
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.all_tenants', true) = 'on'
+    );
+
+ALTER TABLE budgets DROP CONSTRAINT budgets_pkey;
+
+ALTER TABLE budgets
+    ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' CHECK (tenant_id <> '');
+
+INSERT INTO budgets (tenant_id, user_id, monthly_limit, enforcement, created_at, updated_at)
+SELECT u.tenant_id, b.user_id, b.monthly_limit, b.enforcement, b.created_at, b.updated_at
+FROM budgets b
+JOIN users u ON u.id = b.user_id AND u.tenant_id <> 'default';
+
+ALTER TABLE budgets ADD PRIMARY KEY (tenant_id, user_id);
+
+ALTER TABLE budgets
+    ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id', true);
+
+ALTER TABLE budgets ENABLE ROW LEVEL SECURITY;
+ALTER TABLE budgets FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON budgets
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.all_tenants', true) = 'on'
+    );
+
+-- +goose Down
+-- A user or budget present in several tenants keeps a single row.
+DROP POLICY IF EXISTS tenant_isolation ON budgets;
+ALTER TABLE budgets NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE budgets DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE budgets DROP CONSTRAINT budgets_pkey;
+DELETE FROM budgets a USING budgets b
+WHERE a.user_id = b.user_id AND a.tenant_id > b.tenant_id;
+ALTER TABLE budgets DROP COLUMN tenant_id;
+ALTER TABLE budgets ADD PRIMARY KEY (user_id);
+
+DROP POLICY IF EXISTS tenant_isolation ON users;
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE users DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE subscriptions DROP CONSTRAINT fk_subscriptions_user;
+ALTER TABLE users DROP CONSTRAINT users_pkey;
+
+DROP INDEX IF EXISTS idx_users_email;
+DELETE FROM users a USING users b
+WHERE a.id = b.id AND a.tenant_id > b.tenant_id;
+ALTER TABLE users DROP COLUMN tenant_id;
+ALTER TABLE users ADD PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX idx_users_email ON users(lower(email)) WHERE email IS NOT NULL;
+
+ALTER TABLE subscriptions
+    ADD CONSTRAINT fk_subscriptions_user
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
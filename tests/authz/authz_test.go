@@ -0,0 +1,116 @@
+package authz_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/authz"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+)
+
+const (
+	ownerID = "123e4567-e89b-12d3-a456-426614174000"
+	otherID = "123e4567-e89b-12d3-a456-426614174002"
+)
+
+func as(principal *model.Principal) context.Context {
+	return authz.WithPrincipal(context.Background(), principal)
+}
+
+func TestDefaultPolicy_UserOwnDataOnly(t *testing.T) {
+	policy := authz.DefaultPolicy()
+	ctx := as(&model.Principal{Subject: ownerID})
+
+	assert.NoError(t, policy.Authorize(ctx, authz.ActionWrite, ownerID))
+	assert.NoError(t, policy.Authorize(ctx, authz.ActionDelete, ownerID))
+	assert.ErrorIs(t, policy.Authorize(ctx, authz.ActionRead, otherID), authz.ErrForbidden)
+	// Все пользователи сразу доступны только ролям с правами на чужие данные
+	assert.ErrorIs(t, policy.Authorize(ctx, authz.ActionRead, ""), authz.ErrForbidden)
+	assert.ErrorIs(t, policy.Authorize(ctx, authz.ActionRestore, ownerID), authz.ErrForbidden)
+}
+
+func TestDefaultPolicy_SupportReadsAndRestoresAny(t *testing.T) {
+	policy := authz.DefaultPolicy()
+	ctx := as(&model.Principal{Subject: ownerID, Roles: []string{authz.RoleSupport}})
+
+	assert.NoError(t, policy.Authorize(ctx, authz.ActionRead, otherID))
+	assert.NoError(t, policy.Authorize(ctx, authz.ActionRestore, otherID))
+	assert.ErrorIs(t, policy.Authorize(ctx, authz.ActionWrite, otherID), authz.ErrForbidden)
+	assert.ErrorIs(t, policy.Authorize(ctx, authz.ActionDelete, otherID), authz.ErrForbidden)
+}
+
+func TestDefaultPolicy_ServiceKeysCannotDeleteOrRestore(t *testing.T) {
+	policy := authz.DefaultPolicy()
+	ctx := as(&model.Principal{Subject: "api-key:1", Roles: []string{authz.RoleService}})
+
+	assert.NoError(t, policy.Authorize(ctx, authz.ActionRead, otherID))
+	assert.NoError(t, policy.Authorize(ctx, authz.ActionWrite, otherID))
+	assert.NoError(t, policy.Authorize(ctx, authz.ActionUsersWrite, ""))
+	assert.NoError(t, policy.Authorize(ctx, authz.ActionWebhooksWrite, ""))
+	// Удаление и восстановление остаются за людьми с соответствующими ролями
+	assert.ErrorIs(t, policy.Authorize(ctx, authz.ActionDelete, otherID), authz.ErrForbidden)
+	assert.ErrorIs(t, policy.Authorize(ctx, authz.ActionRestore, otherID), authz.ErrForbidden)
+}
+
+func TestDefaultPolicy_AdminDoesEverything(t *testing.T) {
+	policy := authz.DefaultPolicy()
+
+	// Администратор определяется и по флагу аутентификации, и по роли
+	for _, principal := range []*model.Principal{
+		{Subject: ownerID, Admin: true},
+		{Subject: ownerID, Roles: []string{authz.RoleAdmin}},
+	} {
+		ctx := as(principal)
+		assert.NoError(t, policy.Authorize(ctx, authz.ActionDelete, otherID))
+		assert.NoError(t, policy.Authorize(ctx, authz.ActionRestore, ""))
+	}
+}
+
+func TestPolicy_RequiresPrincipal(t *testing.T) {
+	policy := authz.DefaultPolicy()
+
+	assert.ErrorIs(t, policy.Authorize(context.Background(), authz.ActionRead, ownerID), authz.ErrForbidden)
+	assert.NoError(t, policy.Authorize(authz.SystemContext(context.Background()), authz.ActionRead, ""))
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{
+		"default_role": "viewer",
+		"roles": {"viewer": {"own": ["subscriptions:read"]}}
+	}`), 0o600))
+
+	policy, err := authz.LoadPolicy(path)
+	assert.NoError(t, err)
+
+	ctx := as(&model.Principal{Subject: ownerID, Roles: []string{"unknown"}})
+	assert.NoError(t, policy.Authorize(ctx, authz.ActionRead, ownerID))
+	assert.ErrorIs(t, policy.Authorize(ctx, authz.ActionWrite, ownerID), authz.ErrForbidden)
+}
+
+func TestLoadPolicy_Invalid(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"unknown_action.json":  `{"default_role": "user", "roles": {"user": {"own": ["subscriptions:archive"]}}}`,
+		"missing_default.json": `{"default_role": "guest", "roles": {"user": {"own": ["subscriptions:read"]}}}`,
+		"malformed.json":       `{"roles":`,
+	} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		_, err := authz.LoadPolicy(path)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestLoadPolicy_ShippedFile(t *testing.T) {
+	policy, err := authz.LoadPolicy("../../configs/rbac_policy.json")
+
+	assert.NoError(t, err)
+	assert.Equal(t, authz.DefaultPolicy(), policy)
+}
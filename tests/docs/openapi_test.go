@@ -0,0 +1,80 @@
+package docs_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/t5129001t-jpg/subscription-service/docs"
+	"github.com/t5129001t-jpg/subscription-service/internal/server"
+)
+
+// undocumented — маршруты, которых намеренно нет в спецификации
+var undocumented = map[string]bool{
+	"GET /swagger/index.html": true,
+}
+
+func routerRoutes() []string {
+	gin.SetMode(gin.TestMode)
+	next := func(c *gin.Context) { c.Next() }
+	router := server.NewRouter(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, next, nil, next, next, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var routes []string
+	for _, route := range router.Routes() {
+		key := route.Method + " " + docs.PathFromRoute(route.Path)
+		if !undocumented[key] {
+			routes = append(routes, key)
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+func specRoutes(spec *openapi3.T) []string {
+	var routes []string
+	for path, item := range spec.Paths.Map() {
+		for method := range item.Operations() {
+			routes = append(routes, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+func TestSpec_IsValid(t *testing.T) {
+	spec, err := docs.NewSpec()
+	require.NoError(t, err)
+	assert.NoError(t, spec.Validate(context.Background()))
+}
+
+func TestSpec_MatchesRouter(t *testing.T) {
+	// Каждый маршрут роутера описан в спецификации, и наоборот
+	spec, err := docs.NewSpec()
+	require.NoError(t, err)
+
+	assert.Equal(t, routerRoutes(), specRoutes(spec))
+}
+
+func TestSpec_RequestSchemasFollowBindingTags(t *testing.T) {
+	spec, err := docs.NewSpec()
+	require.NoError(t, err)
+
+	create := spec.Components.Schemas["CreateSubscriptionRequest"].Value
+	assert.ElementsMatch(t, []string{"service_name", "price", "user_id", "start_date"}, create.Required)
+	assert.Equal(t, "uuid", create.Properties["user_id"].Value.Format)
+	assert.Equal(t, float64(1), *create.Properties["billing_day"].Value.Min)
+	assert.Equal(t, float64(31), *create.Properties["billing_day"].Value.Max)
+	assert.NotEmpty(t, create.Properties["start_date"].Value.Pattern)
+
+	// Правила после dive относятся к элементам массива
+	webhook := spec.Components.Schemas["CreateWebhookRequest"].Value
+	assert.Equal(t, uint64(1), webhook.Properties["event_types"].Value.MinItems)
+	assert.Len(t, webhook.Properties["event_types"].Value.Items.Value.Enum, 4)
+}
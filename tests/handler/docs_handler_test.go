@@ -0,0 +1,50 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/t5129001t-jpg/subscription-service/docs"
+	"github.com/t5129001t-jpg/subscription-service/internal/handler"
+)
+
+func newDocsRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	spec, err := docs.NewSpec()
+	require.NoError(t, err)
+	h := handler.NewDocsHandler(spec)
+
+	router := gin.New()
+	router.GET("/openapi.json", h.OpenAPI)
+	router.GET("/swagger/index.html", h.SwaggerUI)
+	return router
+}
+
+func TestOpenAPI_ServesSpec(t *testing.T) {
+	router := newDocsRouter(t)
+
+	w := probe(router, "/openapi.json")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+	assert.Contains(t, spec.Paths["/api/v1/subscriptions/{id}"], "get")
+}
+
+func TestSwaggerUI_LoadsSpec(t *testing.T) {
+	router := newDocsRouter(t)
+
+	w := probe(router, "/swagger/index.html")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `url: "/openapi.json"`)
+}
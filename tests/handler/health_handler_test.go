@@ -0,0 +1,89 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/handler"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+)
+
+// stubHealthRepository отвечает заданными результатами проверок
+type stubHealthRepository struct {
+	pingErr error
+	version int64
+}
+
+func (r *stubHealthRepository) Ping(ctx context.Context) error {
+	return r.pingErr
+}
+
+func (r *stubHealthRepository) MigrationVersion(ctx context.Context) (int64, error) {
+	return r.version, nil
+}
+
+func newHealthRouter(repo *stubHealthRepository) (*gin.Engine, service.HealthService) {
+	gin.SetMode(gin.TestMode)
+
+	svc := service.NewHealthService(repo, 16, time.Second)
+	h := handler.NewHealthHandler(svc)
+
+	router := gin.New()
+	router.GET("/healthz", h.Healthz)
+	router.GET("/readyz", h.Readyz)
+	return router, svc
+}
+
+func probe(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestReadyz_Ready(t *testing.T) {
+	router, _ := newHealthRouter(&stubHealthRepository{version: 16})
+
+	w := probe(router, "/readyz")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ready","checks":{"database":"ok","migrations":"ok","shutdown":"ok"}}`, w.Body.String())
+}
+
+func TestReadyz_ReadyAheadOfMigrations(t *testing.T) {
+	// Новая реплика уже применила свою миграцию, старая остаётся готовой
+	router, _ := newHealthRouter(&stubHealthRepository{version: 17})
+
+	assert.Equal(t, http.StatusOK, probe(router, "/readyz").Code)
+}
+
+func TestReadyz_NotReady(t *testing.T) {
+	// Миграции ещё не применены
+	router, _ := newHealthRouter(&stubHealthRepository{version: 15})
+	w := probe(router, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"migrations":"at version 15, expected 16"`)
+
+	// База недоступна, но процесс жив
+	router, _ = newHealthRouter(&stubHealthRepository{pingErr: errors.New("dial tcp: connection refused")})
+	w = probe(router, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"database":"unreachable"`)
+	assert.Equal(t, http.StatusOK, probe(router, "/healthz").Code)
+}
+
+func TestReadyz_Draining(t *testing.T) {
+	router, svc := newHealthRouter(&stubHealthRepository{version: 16})
+
+	// После начала остановки сервис перестаёт принимать трафик
+	svc.Drain()
+	w := probe(router, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"shutdown":"shutting down"`)
+	assert.Equal(t, http.StatusOK, probe(router, "/healthz").Code)
+}
@@ -0,0 +1,117 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/authz"
+	"github.com/t5129001t-jpg/subscription-service/internal/config"
+	"github.com/t5129001t-jpg/subscription-service/internal/handler"
+	"github.com/t5129001t-jpg/subscription-service/internal/middleware"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/problem"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
+)
+
+func TestStreamSubscriptions_SendsServerSentEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	feed := service.NewChangeFeed()
+	source := make(chan model.SubscriptionChange, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go feed.Run(ctx, source)
+
+	router := gin.New()
+	router.Use(middleware.ResolveTenant(config.TenantConfig{Header: "X-Tenant-ID", Default: tenant.Default}))
+	router.GET("/api/v1/subscriptions/stream", handler.NewStreamHandler(feed, nil, time.Minute).StreamSubscriptions)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	userID := "123e4567-e89b-12d3-a456-426614174000"
+	resp, err := http.Get(server.URL + "/api/v1/subscriptions/stream?user_id=" + userID)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	// Заголовки получены, значит клиент уже подписан на ленту
+	source <- model.SubscriptionChange{Event: model.EventSubscriptionCreated, ID: "sub-1", TenantID: tenant.Default, UserID: userID}
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) == 0 || lines[len(lines)-1] != "" {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		lines = append(lines, strings.TrimRight(line, "\n"))
+	}
+
+	assert.Contains(t, lines, "event:subscription.created")
+	assert.True(t, strings.HasPrefix(lines[len(lines)-2], "data:"))
+	assert.Contains(t, lines[len(lines)-2], `"id":"sub-1"`)
+}
+
+func TestStreamSubscriptions_InvalidUserID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/api/v1/subscriptions/stream", handler.NewStreamHandler(service.NewChangeFeed(), nil, time.Minute).StreamSubscriptions)
+
+	req, _ := http.NewRequest("GET", "/api/v1/subscriptions/stream?user_id=not-a-uuid", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Ошибка отдаётся в формате problem+json с указанием параметра
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `"field":"query.user_id"`)
+}
+
+func TestStreamSubscriptions_AuthorizedByPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	feed := service.NewChangeFeed()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go feed.Run(ctx, make(chan model.SubscriptionChange))
+
+	userID := "123e4567-e89b-12d3-a456-426614174000"
+	otherUserID := "123e4567-e89b-12d3-a456-426614174001"
+
+	var principal *model.Principal
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(authz.WithPrincipal(c.Request.Context(), principal))
+	})
+	router.Use(middleware.ResolveTenant(config.TenantConfig{Header: "X-Tenant-ID", Default: tenant.Default}))
+	router.GET("/api/v1/subscriptions/stream", handler.NewStreamHandler(feed, authz.DefaultPolicy(), time.Minute).StreamSubscriptions)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	status := func(query string) int {
+		resp, err := http.Get(server.URL + "/api/v1/subscriptions/stream" + query)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Пользователь слушает свои подписки и общие с ним, но не чужие
+	principal = &model.Principal{Subject: userID}
+	assert.Equal(t, http.StatusOK, status(""))
+	assert.Equal(t, http.StatusOK, status("?user_id="+userID))
+	assert.Equal(t, http.StatusForbidden, status("?user_id="+otherUserID))
+
+	// Поддержка читает любые подписки, не будучи администратором
+	principal = &model.Principal{Subject: "support-agent", Roles: []string{authz.RoleSupport}}
+	assert.Equal(t, http.StatusOK, status("?user_id="+otherUserID))
+	assert.Equal(t, http.StatusOK, status(""))
+}
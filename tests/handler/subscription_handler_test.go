@@ -2,12 +2,20 @@ package handler_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/t5129001t-jpg/subscription-service/internal/handler"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/problem"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
 )
 
 func TestCreateSubscriptionRoute(t *testing.T) {
@@ -39,3 +47,100 @@ func TestCreateSubscriptionRoute(t *testing.T) {
 	// Проверяем результат
 	assert.Equal(t, http.StatusCreated, w.Code)
 }
+
+// stubSubscriptionService возвращает заданную ошибку из Create и Cancel;
+// остальные методы не реализованы
+type stubSubscriptionService struct {
+	service.SubscriptionService
+	err error
+}
+
+func (s *stubSubscriptionService) Create(ctx context.Context, req *model.CreateSubscriptionRequest) (*model.Subscription, error) {
+	return nil, s.err
+}
+
+func (s *stubSubscriptionService) Cancel(ctx context.Context, id string, req *model.CancelSubscriptionRequest) (*model.Subscription, error) {
+	return nil, s.err
+}
+
+func newSubscriptionRouter(err error) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	h := handler.NewSubscriptionHandler(&stubSubscriptionService{err: err})
+	router := gin.New()
+	router.POST("/subscriptions", h.CreateSubscription)
+	router.POST("/subscriptions/:id/cancel", h.CancelSubscription)
+	return router
+}
+
+const validSubscription = `{"service_name":"Netflix","price":400,"user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","start_date":"07-2025"}`
+
+func postProblem(t *testing.T, router *gin.Engine, path, body string) (int, map[string]interface{}) {
+	req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	var p map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, float64(w.Code), p["status"])
+	assert.Equal(t, path, p["instance"])
+	return w.Code, p
+}
+
+func TestSubscriptionHandler_BindingErrorsNameJSONFields(t *testing.T) {
+	router := newSubscriptionRouter(nil)
+
+	code, p := postProblem(t, router, "/subscriptions", `{"price":-1,"user_id":"x","start_date":"07-2025"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	var fields []string
+	for _, err := range p["errors"].([]interface{}) {
+		fields = append(fields, err.(map[string]interface{})["field"].(string))
+	}
+	assert.ElementsMatch(t, []string{"body.service_name", "body.price", "body.user_id"}, fields)
+}
+
+func TestSubscriptionHandler_InvalidPathParameter(t *testing.T) {
+	router := newSubscriptionRouter(nil)
+
+	code, p := postProblem(t, router, "/subscriptions/abc/cancel", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "path.id", p["errors"].([]interface{})[0].(map[string]interface{})["field"])
+}
+
+func TestSubscriptionHandler_DuplicateNamesConflictingSubscription(t *testing.T) {
+	router := newSubscriptionRouter(&service.DuplicateSubscriptionError{ConflictingID: "11111111-1111-1111-1111-111111111111"})
+
+	code, p := postProblem(t, router, "/subscriptions", validSubscription)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "Conflict", p["title"])
+	assert.Equal(t, "11111111-1111-1111-1111-111111111111", p["conflicting_id"])
+}
+
+func TestSubscriptionHandler_ValidationErrorNamesField(t *testing.T) {
+	router := newSubscriptionRouter(&service.ValidationError{Field: "effective_month", Message: "invalid effective_month format, expected MM-YYYY"})
+
+	code, p := postProblem(t, router, "/subscriptions/60601fee-2bf1-4721-ae6f-7636e79a0cba/cancel", `{"effective_month":"2025-07"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "body.effective_month", p["errors"].([]interface{})[0].(map[string]interface{})["field"])
+}
+
+func TestSubscriptionHandler_ValidationErrorNamesPathParameter(t *testing.T) {
+	router := newSubscriptionRouter(&service.ValidationError{Field: "id", Message: "id is required"})
+
+	// Поле из пути помечается как path, а не body
+	code, p := postProblem(t, router, "/subscriptions/60601fee-2bf1-4721-ae6f-7636e79a0cba/cancel", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "path.id", p["errors"].([]interface{})[0].(map[string]interface{})["field"])
+}
+
+func TestSubscriptionHandler_HidesUnexpectedErrors(t *testing.T) {
+	// Сообщение Postgres не должно попасть в ответ
+	router := newSubscriptionRouter(errors.New(`pq: relation "subscriptions" does not exist`))
+
+	code, p := postProblem(t, router, "/subscriptions", validSubscription)
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.NotContains(t, p["detail"], "pq:")
+}
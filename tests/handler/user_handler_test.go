@@ -0,0 +1,80 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/authz"
+	"github.com/t5129001t-jpg/subscription-service/internal/handler"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
+)
+
+// stubUserService запоминает, какую страницу у него запросили и во всех ли
+// брендах стирались данные
+type stubUserService struct {
+	service.UserService
+	limit, offset int
+	allTenants    bool
+}
+
+func (s *stubUserService) List(ctx context.Context, limit, offset int) ([]model.User, int, error) {
+	s.limit, s.offset = limit, offset
+	return []model.User{}, 0, nil
+}
+
+func (s *stubUserService) EraseData(ctx context.Context, id, mode string) (*model.ErasureReceipt, error) {
+	s.allTenants = tenant.IsAllTenants(ctx)
+	return &model.ErasureReceipt{Mode: mode}, nil
+}
+
+func TestListUsers_ReportsAppliedPage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := &stubUserService{}
+	router := gin.New()
+	router.GET("/users", handler.NewUserHandler(svc, nil).ListUsers)
+
+	req, _ := http.NewRequest("GET", "/users?limit=5000&offset=-1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// В ответе — лимит и смещение, с которыми список действительно выбран
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":[],"total":0,"limit":100,"offset":0}`, w.Body.String())
+	assert.Equal(t, 100, svc.limit)
+	assert.Equal(t, 0, svc.offset)
+}
+
+func TestEraseUserData_AllTenantsOnlyForAdmins(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const path = "/users/123e4567-e89b-12d3-a456-426614174000/data?tenants=all"
+	for _, admin := range []bool{false, true} {
+		svc := &stubUserService{}
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			principal := &model.Principal{Subject: "caller", Admin: admin}
+			c.Request = c.Request.WithContext(authz.WithPrincipal(c.Request.Context(), principal))
+		})
+		router.DELETE("/users/:user_id/data", handler.NewUserHandler(svc, nil).EraseUserData)
+
+		req, _ := http.NewRequest("DELETE", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		// Стереть данные во всех брендах может только администратор
+		if admin {
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.True(t, svc.allTenants)
+		} else {
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.False(t, svc.allTenants)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/metrics"
+)
+
+type stubCounter struct {
+	counts map[string]map[string]int
+	err    error
+}
+
+func (s *stubCounter) CountByStatus(ctx context.Context) (map[string]map[string]int, error) {
+	return s.counts, s.err
+}
+
+func TestSubscriptionCollector(t *testing.T) {
+	collector := metrics.NewSubscriptionCollector(&stubCounter{counts: map[string]map[string]int{
+		"default": {"active": 3, "paused": 1},
+		"brand-a": {"active": 2},
+	}}, time.Second)
+
+	expected := `
+# HELP subscription_service_subscriptions Subscriptions by tenant and effective status.
+# TYPE subscription_service_subscriptions gauge
+subscription_service_subscriptions{status="active",tenant="brand-a"} 2
+subscription_service_subscriptions{status="active",tenant="default"} 3
+subscription_service_subscriptions{status="paused",tenant="default"} 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+}
+
+func TestSubscriptionCollector_CountFails(t *testing.T) {
+	collector := metrics.NewSubscriptionCollector(&stubCounter{err: errors.New("connection refused")}, time.Second)
+
+	// Без данных о подписках сбор метрик не падает
+	assert.Equal(t, 0, testutil.CollectAndCount(collector))
+}
@@ -0,0 +1,207 @@
+package middleware_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/authz"
+	"github.com/t5129001t-jpg/subscription-service/internal/config"
+	"github.com/t5129001t-jpg/subscription-service/internal/middleware"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+)
+
+const (
+	testSecret = "test-secret-test-secret-test-secret"
+	testUserID = "123e4567-e89b-12d3-a456-426614174000"
+)
+
+func testAuthConfig() config.AuthConfig {
+	return config.AuthConfig{
+		Enabled:     true,
+		HS256Secret: testSecret,
+		RolesClaim:  "roles",
+		AdminRole:   "admin",
+	}
+}
+
+func signHS256(t *testing.T, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	assert.NoError(t, err)
+	return token
+}
+
+// newRouter возвращает роутер, отдающий субъект и признак администратора
+func newRouter(t *testing.T, cfg config.AuthConfig, handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	verifier, err := middleware.NewJWTVerifier(cfg)
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.Use(middleware.Authenticate(verifier, nil))
+	handlers = append(handlers, func(c *gin.Context) {
+		principal := middleware.PrincipalFrom(c)
+		c.JSON(http.StatusOK, gin.H{"subject": principal.Subject, "admin": principal.Admin})
+	})
+	router.GET("/users/:user_id", handlers...)
+	return router
+}
+
+func get(router *gin.Engine, path, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthenticate_ValidHS256Token(t *testing.T) {
+	router := newRouter(t, testAuthConfig())
+	token := signHS256(t, jwt.MapClaims{
+		"sub":   testUserID,
+		"roles": []string{"admin"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+
+	w := get(router, "/users/"+testUserID, token)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"subject":"`+testUserID+`","admin":true}`, w.Body.String())
+}
+
+func TestAuthenticate_RejectsMissingAndExpiredTokens(t *testing.T) {
+	router := newRouter(t, testAuthConfig())
+
+	// Без токена
+	w := get(router, "/users/"+testUserID, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+
+	// Просроченный токен
+	expired := signHS256(t, jwt.MapClaims{"sub": testUserID, "exp": time.Now().Add(-time.Hour).Unix()})
+	w = get(router, "/users/"+testUserID, expired)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Токен, подписанный другим ключом
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": testUserID, "exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("another-secret"))
+	w = get(router, "/users/"+testUserID, forged)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestNewJWTVerifier_RejectsWeakHS256Secret(t *testing.T) {
+	// Короткий секрет и пример из .env.example не принимаются
+	for _, secret := range []string{"short-secret", "change-me-to-a-long-random-secret"} {
+		cfg := testAuthConfig()
+		cfg.HS256Secret = secret
+		_, err := middleware.NewJWTVerifier(cfg)
+		assert.Error(t, err, secret)
+	}
+}
+
+func TestAuthorize_OnlyOwnDataUnlessPolicyAllows(t *testing.T) {
+	authorize := middleware.Authorize(authz.DefaultPolicy(), authz.ActionUsersRead, authz.ActionUsersWrite, "user_id")
+	router := newRouter(t, testAuthConfig(), authorize)
+	otherUserID := "123e4567-e89b-12d3-a456-426614174001"
+	exp := time.Now().Add(time.Hour).Unix()
+
+	user := signHS256(t, jwt.MapClaims{"sub": testUserID, "exp": exp})
+	assert.Equal(t, http.StatusOK, get(router, "/users/"+testUserID, user).Code)
+	assert.Equal(t, http.StatusForbidden, get(router, "/users/"+otherUserID, user).Code)
+
+	// Поддержка читает чужие подписки, но не данные других пользователей
+	support := signHS256(t, jwt.MapClaims{"sub": testUserID, "roles": "support", "exp": exp})
+	assert.Equal(t, http.StatusForbidden, get(router, "/users/"+otherUserID, support).Code)
+
+	admin := signHS256(t, jwt.MapClaims{"sub": testUserID, "roles": "support admin", "exp": exp})
+	assert.Equal(t, http.StatusOK, get(router, "/users/"+otherUserID, admin).Code)
+}
+
+func TestAuthenticate_RS256KeyFromJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	// Публикуем открытый ключ в локальном JWKS-файле
+	jwks, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "key-1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, jwks, 0o600))
+
+	router := newRouter(t, config.AuthConfig{JWKSFile: path, RolesClaim: "roles", AdminRole: "admin"})
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub": testUserID,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+
+	w := get(router, "/users/"+testUserID, signed)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"subject":"`+testUserID+`","admin":false}`, w.Body.String())
+}
+
+// Заглушка сервиса API-ключей с одним ключом только на чтение
+type stubAPIKeyService struct {
+	service.APIKeyService
+}
+
+func (stubAPIKeyService) Authenticate(ctx context.Context, key string) (*model.Principal, error) {
+	if key != "ssk_reader" {
+		return nil, service.ErrInvalidAPIKey
+	}
+	return &model.Principal{Subject: "api-key:1", Roles: []string{authz.RoleService}, Scopes: []string{model.ScopeSubscriptionsRead}}, nil
+}
+
+func TestAuthenticate_APIKeyScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	verifier, err := middleware.NewJWTVerifier(testAuthConfig())
+	assert.NoError(t, err)
+
+	router := gin.New()
+	group := router.Group("/subscriptions",
+		middleware.Authenticate(verifier, stubAPIKeyService{}),
+		middleware.RequireScopes(model.ScopeSubscriptionsRead, model.ScopeSubscriptionsWrite))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	group.GET("", ok)
+	group.POST("", ok)
+
+	send := func(method, key string) int {
+		req, _ := http.NewRequest(method, "/subscriptions", nil)
+		req.Header.Set("Authorization", "ApiKey "+key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("GET", "ssk_reader"))
+	// Для записи нужен scope subscriptions:write
+	assert.Equal(t, http.StatusForbidden, send("POST", "ssk_reader"))
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "ssk_unknown"))
+}
@@ -0,0 +1,107 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/middleware"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+)
+
+// memoryIdempotencyService хранит ключи в памяти
+type memoryIdempotencyService struct {
+	hashes    map[string]string
+	responses map[string]*model.StoredResponse
+}
+
+func newMemoryIdempotencyService() *memoryIdempotencyService {
+	return &memoryIdempotencyService{hashes: map[string]string{}, responses: map[string]*model.StoredResponse{}}
+}
+
+func (s *memoryIdempotencyService) Begin(ctx context.Context, scope, key, requestHash string) (*model.StoredResponse, error) {
+	id := scope + "/" + key
+	hash, ok := s.hashes[id]
+	switch {
+	case !ok:
+		s.hashes[id] = requestHash
+		return nil, nil
+	case hash != requestHash:
+		return nil, service.ErrIdempotencyKeyReused
+	case s.responses[id] == nil:
+		return nil, service.ErrIdempotencyKeyInProgress
+	}
+	return s.responses[id], nil
+}
+
+func (s *memoryIdempotencyService) Complete(ctx context.Context, scope, key string, response *model.StoredResponse) error {
+	s.responses[scope+"/"+key] = response
+	return nil
+}
+
+func (s *memoryIdempotencyService) Release(ctx context.Context, scope, key string) error {
+	delete(s.hashes, scope+"/"+key)
+	return nil
+}
+
+func (s *memoryIdempotencyService) RunCleanup(ctx context.Context, interval time.Duration) {}
+
+// newIdempotentRouter возвращает роутер, создающий подписку с номером по порядку
+func newIdempotentRouter(status int) (*gin.Engine, *int) {
+	gin.SetMode(gin.TestMode)
+
+	created := 0
+	router := gin.New()
+	router.POST("/subscriptions", middleware.Idempotency(newMemoryIdempotencyService()), func(c *gin.Context) {
+		created++
+		c.JSON(status, gin.H{"number": created})
+	})
+	return router, &created
+}
+
+func postWithKey(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/subscriptions", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReplaysResponse(t *testing.T) {
+	router, created := newIdempotentRouter(http.StatusCreated)
+
+	first := postWithKey(router, "key-1", `{"price":100}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	// Повтор с тем же ключом возвращает исходный ответ, не создавая подписку
+	retry := postWithKey(router, "key-1", `{"price":100}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.JSONEq(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 1, *created)
+
+	// Тот же ключ с другим телом отклоняется
+	assert.Equal(t, http.StatusUnprocessableEntity, postWithKey(router, "key-1", `{"price":200}`).Code)
+	assert.Equal(t, 1, *created)
+}
+
+func TestIdempotency_WithoutKeyOrAfterServerError(t *testing.T) {
+	router, created := newIdempotentRouter(http.StatusCreated)
+	postWithKey(router, "", `{}`)
+	postWithKey(router, "", `{}`)
+	assert.Equal(t, 2, *created)
+
+	// Ответ 5xx не сохраняется, и запрос можно повторить
+	router, created = newIdempotentRouter(http.StatusInternalServerError)
+	postWithKey(router, "key-1", `{}`)
+	postWithKey(router, "key-1", `{}`)
+	assert.Equal(t, 2, *created)
+}
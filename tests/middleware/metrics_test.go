@@ -0,0 +1,56 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/metrics"
+	"github.com/t5129001t-jpg/subscription-service/internal/middleware"
+)
+
+func TestMetrics_CountsByRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.Metrics())
+	router.GET("/subscriptions/:id", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+
+	requests := metrics.HTTPRequests.WithLabelValues("GET", "/subscriptions/:id", "404")
+	unmatched := metrics.HTTPRequests.WithLabelValues("GET", "unmatched", "404")
+	before, beforeUnmatched := testutil.ToFloat64(requests), testutil.ToFloat64(unmatched)
+
+	for _, path := range []string{"/subscriptions/1", "/subscriptions/2", "/unknown"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Запросы группируются по шаблону маршрута, а не по пути
+	assert.Equal(t, before+2, testutil.ToFloat64(requests))
+	assert.Equal(t, beforeUnmatched+1, testutil.ToFloat64(unmatched))
+}
+
+func TestMetrics_CountsRecoveredPanics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.Metrics())
+	router.Use(middleware.Recover())
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	requests := metrics.HTTPRequests.WithLabelValues("GET", "/panic", "500")
+	before := testutil.ToFloat64(requests)
+
+	req, _ := http.NewRequest("GET", "/panic", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Паника учитывается как ответ 500
+	assert.Equal(t, before+1, testutil.ToFloat64(requests))
+}
@@ -0,0 +1,111 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/config"
+	"github.com/t5129001t-jpg/subscription-service/internal/middleware"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+)
+
+func newRateLimitedRouter(limiter *middleware.RateLimiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/limited", limiter.Limit("subscriptions"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func getFrom(router *gin.Engine, ip string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/limited", nil)
+	req.RemoteAddr = ip + ":1234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_RejectsOverBurst(t *testing.T) {
+	limiter := middleware.NewRateLimiter(service.NewMemoryRateLimitStore(), map[string]config.RateLimit{
+		"subscriptions": {PerMinute: 1, Burst: 2},
+	})
+	router := newRateLimitedRouter(limiter)
+
+	w := getFrom(router, "10.0.0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1;w=60;burst=2", w.Header().Get("RateLimit-Policy"))
+
+	assert.Equal(t, http.StatusOK, getFrom(router, "10.0.0.1").Code)
+
+	// Третий запрос превышает лимит: следующий токен появится через минуту
+	w = getFrom(router, "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	// У другого клиента своя корзина
+	assert.Equal(t, http.StatusOK, getFrom(router, "10.0.0.2").Code)
+}
+
+func TestRateLimit_DisabledOrUnlimitedGroup(t *testing.T) {
+	var disabled *middleware.RateLimiter
+	router := newRateLimitedRouter(disabled)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, getFrom(router, "10.0.0.1").Code)
+	}
+
+	// Группа без настроенного лимита не ограничивается
+	router = newRateLimitedRouter(middleware.NewRateLimiter(service.NewMemoryRateLimitStore(), nil))
+	w := getFrom(router, "10.0.0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+}
+
+func TestLimitFailedAuth_CountsOnlyRejectedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := middleware.NewRateLimiter(service.NewMemoryRateLimitStore(), map[string]config.RateLimit{
+		"auth": {PerMinute: 1, Burst: 2},
+	})
+	router := gin.New()
+	router.Use(limiter.LimitFailedAuth("auth"), func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer valid" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+		}
+	})
+	router.GET("/limited", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	send := func(ip, authorization string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/limited", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Успешные запросы не расходуют лимит
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, send("10.0.0.1", "Bearer valid").Code)
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, send("10.0.0.1", "Bearer guess-1").Code)
+	assert.Equal(t, http.StatusUnauthorized, send("10.0.0.1", "Bearer guess-2").Code)
+
+	// После исчерпания лимита отклоняются все запросы с этого адреса
+	w := send("10.0.0.1", "Bearer valid")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	// Другой адрес не затронут
+	assert.Equal(t, http.StatusOK, send("10.0.0.2", "Bearer valid").Code)
+}
@@ -0,0 +1,98 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/logging"
+	"github.com/t5129001t-jpg/subscription-service/internal/middleware"
+	"github.com/t5129001t-jpg/subscription-service/internal/problem"
+)
+
+// newLoggedRouter возвращает роутер, пишущий JSON-логи в buf
+func newLoggedRouter(buf *bytes.Buffer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.RequestLogger(logging.New(buf, slog.LevelDebug)))
+	router.Use(middleware.Recover())
+	router.GET("/subscriptions/:id", func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Info("loading subscription")
+		c.Status(http.StatusOK)
+	})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	return router
+}
+
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestRequestLogger_PropagatesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	router := newLoggedRouter(&buf)
+
+	req, _ := http.NewRequest("GET", "/subscriptions/42", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "req-123", w.Header().Get("X-Request-ID"))
+
+	// Логи обработчика и итоговая запись о запросе несут один request_id
+	lines := logLines(t, &buf)
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "loading subscription", lines[0]["msg"])
+	assert.Equal(t, "req-123", lines[0]["request_id"])
+	assert.Equal(t, "request", lines[1]["msg"])
+	assert.Equal(t, "req-123", lines[1]["request_id"])
+	assert.Equal(t, "/subscriptions/:id", lines[1]["route"])
+	assert.Equal(t, float64(http.StatusOK), lines[1]["status"])
+}
+
+func TestRequestLogger_GeneratesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	router := newLoggedRouter(&buf)
+
+	// Недопустимый идентификатор заменяется сгенерированным
+	req, _ := http.NewRequest("GET", "/subscriptions/42", nil)
+	req.Header.Set("X-Request-ID", "bad id\n")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	requestID := w.Header().Get("X-Request-ID")
+	assert.Len(t, requestID, 32)
+	assert.Equal(t, requestID, logLines(t, &buf)[1]["request_id"])
+}
+
+func TestRecover_LogsPanic(t *testing.T) {
+	var buf bytes.Buffer
+	router := newLoggedRouter(&buf)
+
+	req, _ := http.NewRequest("GET", "/panic", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"An unexpected error occurred.","instance":"/panic"}`, w.Body.String())
+	lines := logLines(t, &buf)
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "panic recovered", lines[0]["msg"])
+	assert.Equal(t, "ERROR", lines[1]["level"])
+}
@@ -0,0 +1,125 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/config"
+	"github.com/t5129001t-jpg/subscription-service/internal/middleware"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
+)
+
+// newTenantRouter возвращает роутер, отдающий арендатора запроса
+func newTenantRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	cfg := testAuthConfig()
+	cfg.TenantClaim = "tenant_id"
+	verifier, err := middleware.NewJWTVerifier(cfg)
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.Use(middleware.Authenticate(verifier, nil))
+	router.Use(middleware.ResolveTenant(config.TenantConfig{Header: "X-Tenant-ID", Default: tenant.Default}))
+	router.GET("/tenant", func(c *gin.Context) {
+		id, _ := tenant.FromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"tenant": id})
+	})
+	return router
+}
+
+func getTenant(router *gin.Engine, token, header string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/tenant", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if header != "" {
+		req.Header.Set("X-Tenant-ID", header)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestResolveTenant_ClaimDecides(t *testing.T) {
+	router := newTenantRouter(t)
+	exp := time.Now().Add(time.Hour).Unix()
+	token := signHS256(t, jwt.MapClaims{"sub": testUserID, "tenant_id": "brand-a", "exp": exp})
+
+	w := getTenant(router, token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"tenant":"brand-a"}`, w.Body.String())
+
+	// Заголовок не может переопределить арендатора из токена
+	assert.Equal(t, http.StatusForbidden, getTenant(router, token, "brand-b").Code)
+}
+
+func TestResolveTenant_HeaderOnlyForAdmins(t *testing.T) {
+	router := newTenantRouter(t)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	user := signHS256(t, jwt.MapClaims{"sub": testUserID, "exp": exp})
+	w := getTenant(router, user, "")
+	assert.JSONEq(t, `{"tenant":"default"}`, w.Body.String())
+	assert.Equal(t, http.StatusForbidden, getTenant(router, user, "brand-b").Code)
+
+	admin := signHS256(t, jwt.MapClaims{"sub": testUserID, "roles": []string{"admin"}, "exp": exp})
+	w = getTenant(router, admin, "brand-b")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"tenant":"brand-b"}`, w.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, getTenant(router, admin, "Brand B").Code)
+}
+
+func TestAuthenticate_RejectsInvalidTenantClaim(t *testing.T) {
+	router := newTenantRouter(t)
+	token := signHS256(t, jwt.MapClaims{"sub": testUserID, "tenant_id": 42, "exp": time.Now().Add(time.Hour).Unix()})
+
+	assert.Equal(t, http.StatusUnauthorized, getTenant(router, token, "").Code)
+}
+
+func TestResolveTenant_APIKeyPinnedToItsTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	verifier, err := middleware.NewJWTVerifier(testAuthConfig())
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.Use(middleware.Authenticate(verifier, tenantAPIKeyService{}))
+	router.Use(middleware.ResolveTenant(config.TenantConfig{Header: "X-Tenant-ID", Default: tenant.Default}))
+	router.GET("/tenant", func(c *gin.Context) {
+		id, _ := tenant.FromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"tenant": id})
+	})
+
+	send := func(header string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/tenant", nil)
+		req.Header.Set("Authorization", "ApiKey ssk_brand_a")
+		if header != "" {
+			req.Header.Set("X-Tenant-ID", header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("")
+	assert.JSONEq(t, `{"tenant":"brand-a"}`, w.Body.String())
+	// Ключ не может выбрать чужого арендатора заголовком
+	assert.Equal(t, http.StatusForbidden, send("brand-b").Code)
+}
+
+// Заглушка сервиса API-ключей с ключом, выданным в brand-a
+type tenantAPIKeyService struct {
+	service.APIKeyService
+}
+
+func (tenantAPIKeyService) Authenticate(ctx context.Context, key string) (*model.Principal, error) {
+	return &model.Principal{Subject: "api-key:2", Scopes: []string{model.ScopeSubscriptionsRead}, Tenant: "brand-a"}, nil
+}
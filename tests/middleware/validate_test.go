@@ -0,0 +1,125 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/t5129001t-jpg/subscription-service/docs"
+	"github.com/t5129001t-jpg/subscription-service/internal/middleware"
+	"github.com/t5129001t-jpg/subscription-service/internal/problem"
+)
+
+// newValidatedRouter возвращает тело запроса, дошедшее до обработчика
+func newValidatedRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	spec, err := docs.NewSpec()
+	require.NoError(t, err)
+
+	echo := func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	}
+	router := gin.New()
+	validate := middleware.ValidateRequest(spec)
+	router.POST("/api/v1/subscriptions/", validate, echo)
+	router.GET("/api/v1/subscriptions/", validate, echo)
+	router.PUT("/api/v1/subscriptions/:id/members/:user_id", validate, echo)
+	router.POST("/api/v1/subscriptions/:id/pause", validate, echo)
+	router.GET("/undocumented", validate, echo)
+	return router
+}
+
+func send(router *gin.Engine, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) problem.Problem {
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	return p
+}
+
+func fields(p problem.Problem) []string {
+	var names []string
+	for _, err := range p.Errors {
+		names = append(names, err.Field)
+	}
+	return names
+}
+
+func TestValidateRequest_PassesValidRequest(t *testing.T) {
+	router := newValidatedRouter(t)
+	body := `{"service_name":"Netflix","price":400,"user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","start_date":"07-2025"}`
+
+	w := send(router, "POST", "/api/v1/subscriptions/", body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	// Тело после проверки доступно обработчику
+	assert.Equal(t, body, w.Body.String())
+}
+
+func TestValidateRequest_ListsEveryInvalidBodyField(t *testing.T) {
+	router := newValidatedRouter(t)
+
+	w := send(router, "POST", "/api/v1/subscriptions/", `{"price":-1,"user_id":"x","start_date":"2025-07"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	p := decodeProblem(t, w)
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, "/api/v1/subscriptions/", p.Instance)
+	assert.ElementsMatch(t, []string{"body.service_name", "body.price", "body.user_id", "body.start_date"}, fields(p))
+}
+
+func TestValidateRequest_ChecksPathAndQuery(t *testing.T) {
+	router := newValidatedRouter(t)
+
+	w := send(router, "PUT", "/api/v1/subscriptions/abc/members/def", `{"share_type":"half","share_value":10}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.ElementsMatch(t, []string{"path.id", "path.user_id", "body.share_type"}, fields(decodeProblem(t, w)))
+
+	w = send(router, "GET", "/api/v1/subscriptions/?status=unknown&limit=ten&month=13-2025", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.ElementsMatch(t, []string{"query.status", "query.limit", "query.month"}, fields(decodeProblem(t, w)))
+}
+
+func TestValidateRequest_RejectsMalformedBody(t *testing.T) {
+	router := newValidatedRouter(t)
+
+	w := send(router, "POST", "/api/v1/subscriptions/", `{"price":`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []string{"body"}, fields(decodeProblem(t, w)))
+
+	// Обязательное тело отсутствует
+	w = send(router, "POST", "/api/v1/subscriptions/", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []string{"body"}, fields(decodeProblem(t, w)))
+}
+
+func TestValidateRequest_AllowsOptionalBody(t *testing.T) {
+	router := newValidatedRouter(t)
+
+	w := send(router, "POST", "/api/v1/subscriptions/60601fee-2bf1-4721-ae6f-7636e79a0cba/pause", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestValidateRequest_SkipsUndocumentedRoutes(t *testing.T) {
+	router := newValidatedRouter(t)
+
+	w := send(router, "GET", "/undocumented?limit=ten", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,59 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+)
+
+const migrationsDir = "../../migrations"
+
+func TestLatestMigrationVersion_RealMigrations(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(migrationsDir, "*.sql"))
+	assert.NoError(t, err)
+
+	// Каждая миграция — один файл со своей версией, последняя — самая старшая
+	version, err := repository.LatestMigrationVersion(migrationsDir)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(files)), version)
+}
+
+func TestMigrations_ParseUpAndDown(t *testing.T) {
+	// Принимаем любой SQL, но не разрезанные посередине функции с $$
+	matcher := sqlmock.QueryMatcherFunc(func(_, actual string) error {
+		if strings.Count(actual, "$$")%2 != 0 {
+			return errors.New("statement split inside a dollar-quoted body: " + actual)
+		}
+		return nil
+	})
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(matcher))
+	assert.NoError(t, err)
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+	for i := 0; i < 500; i++ {
+		mock.ExpectBegin()
+		mock.ExpectExec("").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+	}
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, os.DirFS(migrationsDir),
+		goose.WithDisableVersioning(true))
+	assert.NoError(t, err)
+
+	up, err := provider.Up(context.Background())
+	assert.NoError(t, err)
+	down, err := provider.DownTo(context.Background(), 0)
+	assert.NoError(t, err)
+
+	assert.Len(t, up, len(provider.ListSources()))
+	assert.Len(t, down, len(provider.ListSources()))
+}
@@ -0,0 +1,75 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+)
+
+func TestOutboxRelay_StopsAtFirstFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := repository.NewOutboxRepository(sqlxDB)
+
+	columns := []string{"id", "aggregate_type", "aggregate_id", "event_type", "payload", "created_at", "published_at"}
+	rows := sqlmock.NewRows(columns)
+	for id := int64(1); id <= 3; id++ {
+		rows.AddRow(id, "subscription", "123e4567-e89b-12d3-a456-426614174001", "subscription.updated", []byte(`{}`), time.Now(), nil)
+	}
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	mock.ExpectQuery(`SELECT \* FROM outbox`).
+		WithArgs(10).
+		WillReturnRows(rows)
+	// Помечается опубликованным только событие до первой ошибки
+	mock.ExpectExec(`UPDATE outbox SET published_at = NOW\(\) WHERE id = ANY\(\$1\)`).
+		WithArgs(pq.Array([]int64{1})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	publishErr := errors.New("broker unavailable")
+	published, err := repo.Relay(context.Background(), 10, func(event model.OutboxEvent) error {
+		if event.ID == 2 {
+			return publishErr
+		}
+		return nil
+	})
+
+	assert.ErrorIs(t, err, publishErr)
+	assert.Equal(t, 1, published)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxRelay_SkipsWhileAnotherRelayRuns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewOutboxRepository(sqlx.NewDb(db, "sqlmock"))
+
+	// Блокировку держит другой экземпляр — события не читаются
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+
+	published, err := repo.Relay(context.Background(), 10, func(event model.OutboxEvent) error {
+		t.Fatal("event published without the relay lock")
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, published)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
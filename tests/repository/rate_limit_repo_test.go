@@ -0,0 +1,50 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+)
+
+func TestRateLimitTake(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewRateLimitRepository(sqlx.NewDb(db, "sqlmock"))
+
+	// Корзина пополняется и списывается одной функцией в БД
+	mock.ExpectQuery(`SELECT allowed, tokens_left FROM rate_limit_take\(\$1, \$2, \$3\)`).
+		WithArgs("subscriptions:ip:10.0.0.1", 100, 10.0).
+		WillReturnRows(sqlmock.NewRows([]string{"allowed", "tokens_left"}).AddRow(false, 0.5))
+
+	allowed, tokens, err := repo.Take(context.Background(), "subscriptions:ip:10.0.0.1", 100, 10)
+
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 0.5, tokens)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRateLimitPeek_UnknownBucketIsFull(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewRateLimitRepository(sqlx.NewDb(db, "sqlmock"))
+
+	// Просмотр корзины ничего не списывает; новая корзина полна
+	mock.ExpectQuery(`SELECT COALESCE\(\(\s*SELECT LEAST`).
+		WithArgs("auth:ip:10.0.0.1", 10, 0.5).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(10.0))
+
+	tokens, err := repo.Peek(context.Background(), "auth:ip:10.0.0.1", 10, 0.5)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, tokens)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
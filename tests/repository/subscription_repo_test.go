@@ -1,14 +1,20 @@
 package repository_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/logging"
 	"github.com/t5129001t-jpg/subscription-service/internal/model"
 	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
 )
 
 func TestCreateSubscription(t *testing.T) {
@@ -26,18 +32,151 @@ func TestCreateSubscription(t *testing.T) {
 		Price:       1000,
 		UserID:      "123e4567-e89b-12d3-a456-426614174000",
 		StartDate:   "01-2024",
+		Status:      model.SubscriptionStatusActive,
+		BillingDay:  1,
 	}
 
-	// Ожидаем запрос к БД
+	// Ожидаем запрос к БД и запись события в outbox в той же транзакции
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config\('app.tenant_id', \$1, true\)`).
+		WithArgs("brand-a").
+		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectQuery(`INSERT INTO subscriptions`).
-		WithArgs(sub.ServiceName, sub.Price, sub.UserID, sub.StartDate, nil).
+		WithArgs(sub.ServiceName, sub.Price, sub.UserID, sub.StartDate, nil, nil, sub.Status, sub.BillingDay).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
 			AddRow("123e4567-e89b-12d3-a456-426614174001", time.Now(), time.Now()))
+	mock.ExpectQuery(`SELECT .* FROM subscriptions s WHERE s.id = \$1`).
+		WithArgs("123e4567-e89b-12d3-a456-426614174001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "service_name", "status"}).
+			AddRow("123e4567-e89b-12d3-a456-426614174001", "Netflix", "active"))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(model.AggregateSubscription, "123e4567-e89b-12d3-a456-426614174001", model.EventSubscriptionCreated, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	// Выполняем тестируемую функцию
-	err = repo.Create(sub)
+	err = repo.Create(tenant.WithID(context.Background(), "brand-a"), sub)
 
 	// Проверяем результаты
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestDeleteSubscription_NothingDeletedWritesNoEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := repository.NewSubscriptionRepository(sqlxDB)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+
+	// Подписка уже удалена: событие в outbox не пишется
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE subscriptions SET deleted_at = NOW\(\)`).
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err = repo.Delete(tenant.WithID(context.Background(), tenant.Default), id)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSubscriptionRepository_RequiresTenant(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewSubscriptionRepository(sqlx.NewDb(db, "sqlmock"))
+
+	// Без арендатора в контексте запрос к подпискам не выполняется
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	_, err = repo.GetByID(context.Background(), "123e4567-e89b-12d3-a456-426614174001")
+
+	assert.ErrorIs(t, err, repository.ErrNoTenant)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListBillable_AllTenants(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewSubscriptionRepository(sqlx.NewDb(db, "sqlmock"))
+
+	// Фоновые задачи видят подписки всех арендаторов
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config\('app.all_tenants', 'on', true\)`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT .* FROM subscriptions s`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id"}).
+			AddRow("123e4567-e89b-12d3-a456-426614174001", "brand-a").
+			AddRow("123e4567-e89b-12d3-a456-426614174002", "brand-b"))
+	mock.ExpectCommit()
+
+	subscriptions, err := repo.ListBillable(tenant.AllTenants(context.Background()))
+
+	assert.NoError(t, err)
+	assert.Len(t, subscriptions, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSubscriptionRepository_LogsErrorsWithRequestLogger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewSubscriptionRepository(sqlx.NewDb(db, "sqlmock"))
+
+	var buf bytes.Buffer
+	ctx := logging.WithLogger(context.Background(), logging.New(&buf, slog.LevelInfo).With("request_id", "req-123"))
+	ctx = tenant.WithID(ctx, tenant.Default)
+
+	// Ошибка БД попадает в лог с идентификатором запроса
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config`).WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	_, err = repo.GetByID(ctx, "123e4567-e89b-12d3-a456-426614174001")
+
+	assert.Error(t, err)
+	assert.Contains(t, buf.String(), `"request_id":"req-123"`)
+	assert.Contains(t, buf.String(), `"error":"connection reset"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListPausesFor_GroupsBySubscription(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewSubscriptionRepository(sqlx.NewDb(db, "sqlmock"))
+
+	columns := []string{"id", "subscription_id", "start_month", "end_month", "created_at", "updated_at"}
+	end := "03-2024"
+	rows := sqlmock.NewRows(columns).
+		AddRow("p1", "sub-a", "01-2024", &end, time.Now(), time.Now()).
+		AddRow("p2", "sub-a", "06-2024", nil, time.Now(), time.Now()).
+		AddRow("p3", "sub-b", "02-2024", nil, time.Now(), time.Now())
+
+	// Паузы всех подписок читаются одним запросом
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT \* FROM subscription_pauses\s+WHERE subscription_id = ANY\(\$1::uuid\[\]\)`).
+		WillReturnRows(rows)
+	mock.ExpectCommit()
+
+	pauses, err := repo.ListPausesFor(tenant.WithID(context.Background(), "brand-a"), []string{"sub-a", "sub-b", "sub-c"})
+
+	assert.NoError(t, err)
+	assert.Len(t, pauses["sub-a"], 2)
+	assert.Equal(t, "p2", pauses["sub-a"][1].ID)
+	assert.Len(t, pauses["sub-b"], 1)
+	assert.Empty(t, pauses["sub-c"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,38 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+)
+
+func TestCountByStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewSubscriptionStatsRepository(sqlx.NewDb(db, "sqlmock"))
+
+	// Подсчёт идёт по всем арендаторам
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config\('app.all_tenants', 'on', true\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT tenant_id, status, COUNT\(\*\) AS count`).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "status", "count"}).
+			AddRow("default", "active", 3).
+			AddRow("default", "paused", 1).
+			AddRow("brand-a", "active", 2))
+	mock.ExpectCommit()
+
+	counts, err := repo.CountByStatus(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[string]int{
+		"default": {"active": 3, "paused": 1},
+		"brand-a": {"active": 2},
+	}, counts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,146 @@
+package repository_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
+)
+
+func TestCreateUser_DuplicateEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewUserRepository(sqlx.NewDb(db, "sqlmock"))
+
+	email := "alice@example.com"
+	user := &model.User{Name: "Alice", Email: &email}
+
+	// Нарушение уникального индекса внутри бренда превращается в ErrConflict
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config\('app.tenant_id', \$1, true\)`).
+		WithArgs("brand-a").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO users`).
+		WithArgs("", "Alice", &email).
+		WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	err = repo.Create(tenant.WithID(context.Background(), "brand-a"), user)
+
+	assert.ErrorIs(t, err, repository.ErrConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteUser_StillReferenced(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewUserRepository(sqlx.NewDb(db, "sqlmock"))
+
+	// Пользователь с подписками не удаляется из-за внешнего ключа
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config\('app.tenant_id', \$1, true\)`).
+		WithArgs(tenant.Default).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM users WHERE id = \$1`).
+		WithArgs("123e4567-e89b-12d3-a456-426614174000").
+		WillReturnError(&pq.Error{Code: "23503"})
+	mock.ExpectRollback()
+
+	err = repo.Delete(tenant.WithID(context.Background(), tenant.Default), "123e4567-e89b-12d3-a456-426614174000")
+
+	assert.ErrorIs(t, err, repository.ErrReferenced)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+var erasureHashKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestEraseUserData_DeleteIssuesReceipt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewUserDataRepository(sqlx.NewDb(db, "sqlmock"), erasureHashKey)
+	userID := "123e4567-e89b-12d3-a456-426614174000"
+
+	// Все удаления и квитанция — в одной транзакции, ограниченной брендом
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config\('app.tenant_id', \$1, true\)`).
+		WithArgs("brand-a").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM outbox`).WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`DELETE FROM webhook_deliveries`).WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM budgets`).WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM subscription_members`).WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 0))
+	// О каждой живой подписке потребители узнают из события удаления
+	mock.ExpectQuery(`SELECT id FROM subscriptions WHERE user_id = \$1 AND deleted_at IS NULL`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("123e4567-e89b-12d3-a456-426614174001"))
+	mock.ExpectQuery(`SELECT .* FROM subscriptions s WHERE s.id = \$1`).
+		WithArgs("123e4567-e89b-12d3-a456-426614174001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "service_name", "status"}).
+			AddRow("123e4567-e89b-12d3-a456-426614174001", "Netflix", "active"))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(model.AggregateSubscription, "123e4567-e89b-12d3-a456-426614174001", model.EventSubscriptionDeleted, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`DELETE FROM subscriptions`).WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM users`).WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO erasure_receipts`).
+		WithArgs(sqlmock.AnyArg(), model.ErasureModeDelete, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "erased_at"}).
+			AddRow("123e4567-e89b-12d3-a456-426614174009", time.Now()))
+	mock.ExpectCommit()
+
+	receipt, err := repo.Erase(tenant.WithID(context.Background(), "brand-a"), userID, model.ErasureModeDelete)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "123e4567-e89b-12d3-a456-426614174009", receipt.ID)
+	assert.Equal(t, int64(2), receipt.Counts["subscriptions"])
+	// Квитанция называет пользователя только HMAC его id на ключе сервера
+	mac := hmac.New(sha256.New, erasureHashKey)
+	mac.Write([]byte(userID))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), receipt.SubjectHash)
+	assert.NotContains(t, receipt.SubjectHash, userID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEraseUserData_NothingToErase(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewUserDataRepository(sqlx.NewDb(db, "sqlmock"), erasureHashKey)
+	userID := "123e4567-e89b-12d3-a456-426614174000"
+
+	// Без данных транзакция откатывается и квитанция не выдаётся;
+	// администратор стирает данные сразу во всех брендах
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config\('app.all_tenants', 'on', true\)`).WillReturnResult(sqlmock.NewResult(0, 1))
+	for _, table := range []string{"outbox", "webhook_deliveries", "budgets", "subscription_members"} {
+		mock.ExpectExec(`DELETE FROM ` + table).WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectQuery(`SELECT id FROM subscriptions`).WithArgs(userID).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	for _, table := range []string{"subscriptions", "users"} {
+		mock.ExpectExec(`DELETE FROM ` + table).WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectRollback()
+
+	receipt, err := repo.Erase(tenant.AllTenants(context.Background()), userID, model.ErasureModeDelete)
+
+	assert.NoError(t, err)
+	assert.Nil(t, receipt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,106 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/t5129001t-jpg/subscription-service/internal/authz"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+)
+
+// Мок для репозитория API-ключей
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) List(ctx context.Context) ([]model.APIKey, error) {
+	args := m.Called()
+	return args.Get(0).([]model.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Revoke(ctx context.Context, id string) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	args := m.Called(hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) TouchLastUsed(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func TestIssueAPIKey_StoresOnlyHash(t *testing.T) {
+	mockRepo := new(MockAPIKeyRepository)
+	svc := service.NewAPIKeyService(mockRepo, authz.RoleService)
+
+	var stored *model.APIKey
+	mockRepo.On("Create", mock.AnythingOfType("*model.APIKey")).
+		Run(func(args mock.Arguments) { stored = args.Get(0).(*model.APIKey) }).
+		Return(nil)
+
+	issued, err := svc.Issue(context.Background(), &model.CreateAPIKeyRequest{Name: "nightly-report", Scopes: []string{model.ScopeSubscriptionsRead}})
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(issued.Key, issued.Prefix))
+	// В базу попадает только хеш ключа
+	assert.Len(t, stored.KeyHash, 64)
+	assert.NotContains(t, stored.KeyHash, issued.Key)
+
+	// Выданный ключ находится по своему хешу
+	mockRepo.On("GetByHash", stored.KeyHash).Return(&model.APIKey{ID: "key-1", TenantID: "brand-a", Scopes: stored.Scopes}, nil)
+	mockRepo.On("TouchLastUsed", "key-1").Return(nil)
+
+	principal, err := svc.Authenticate(context.Background(), issued.Key)
+
+	assert.NoError(t, err)
+	// Ключ действует только в арендаторе, где он выдан
+	assert.Equal(t, "brand-a", principal.Tenant)
+	// Права ключа решает политика для его роли, а не флаг администратора
+	assert.False(t, principal.Admin)
+	assert.Equal(t, []string{authz.RoleService}, principal.Roles)
+	assert.True(t, principal.HasScope(model.ScopeSubscriptionsRead))
+	assert.False(t, principal.HasScope(model.ScopeSubscriptionsWrite))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthenticateAPIKey_RejectsExpiredAndRevoked(t *testing.T) {
+	mockRepo := new(MockAPIKeyRepository)
+	svc := service.NewAPIKeyService(mockRepo, authz.RoleService)
+
+	past := time.Now().Add(-time.Hour)
+	mockRepo.On("GetByHash", mock.Anything).Return(&model.APIKey{ID: "key-1", ExpiresAt: &past}, nil).Once()
+	mockRepo.On("GetByHash", mock.Anything).Return(&model.APIKey{ID: "key-2", RevokedAt: &past}, nil).Once()
+	mockRepo.On("GetByHash", mock.Anything).Return(nil, nil).Once()
+
+	for i := 0; i < 3; i++ {
+		_, err := svc.Authenticate(context.Background(), "ssk_unknown")
+		assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+	}
+	mockRepo.AssertNotCalled(t, "TouchLastUsed", mock.Anything)
+}
+
+func TestRevokeAPIKey_NotFound(t *testing.T) {
+	mockRepo := new(MockAPIKeyRepository)
+	svc := service.NewAPIKeyService(mockRepo, authz.RoleService)
+
+	mockRepo.On("Revoke", "key-1").Return(false, nil)
+
+	assert.ErrorIs(t, svc.Revoke(context.Background(), "key-1"), service.ErrAPIKeyNotFound)
+}
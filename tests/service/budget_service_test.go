@@ -0,0 +1,113 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+)
+
+// Мок для репозитория бюджетов
+type MockBudgetRepository struct {
+	mock.Mock
+}
+
+func (m *MockBudgetRepository) Upsert(ctx context.Context, budget *model.Budget) error {
+	args := m.Called(budget)
+	return args.Error(0)
+}
+
+func (m *MockBudgetRepository) GetByUserID(ctx context.Context, userID string) (*model.Budget, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Budget), args.Error(1)
+}
+
+const budgetUserID = "123e4567-e89b-12d3-a456-426614174000"
+
+func TestBudgetCheck_WarnsWhenOverLimit(t *testing.T) {
+	budgetRepo := new(MockBudgetRepository)
+	subRepo := new(MockSubscriptionRepository)
+	svc := service.NewBudgetService(budgetRepo, subRepo)
+
+	budgetRepo.On("GetByUserID", budgetUserID).Return(&model.Budget{MonthlyLimit: 1500, Enforcement: model.BudgetEnforcementWarn}, nil)
+	subRepo.On("GetTotalPrice", budgetUserID, "", "05-2024", "05-2024").Return(1000, nil)
+
+	warning, err := svc.Check(context.Background(), budgetUserID, "05-2024", 800)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1800, warning.ProjectedSpend)
+	assert.Equal(t, 300, warning.ExceededBy)
+}
+
+func TestBudgetCheck_RejectsWhenConfigured(t *testing.T) {
+	budgetRepo := new(MockBudgetRepository)
+	subRepo := new(MockSubscriptionRepository)
+	svc := service.NewBudgetService(budgetRepo, subRepo)
+
+	budgetRepo.On("GetByUserID", budgetUserID).Return(&model.Budget{MonthlyLimit: 1500, Enforcement: model.BudgetEnforcementReject}, nil)
+	subRepo.On("GetTotalPrice", budgetUserID, "", "05-2024", "05-2024").Return(1000, nil)
+
+	_, err := svc.Check(context.Background(), budgetUserID, "05-2024", 800)
+
+	assert.ErrorIs(t, err, service.ErrBudgetExceeded)
+}
+
+func TestBudgetCheck_NoBudgetNoLimit(t *testing.T) {
+	budgetRepo := new(MockBudgetRepository)
+	subRepo := new(MockSubscriptionRepository)
+	svc := service.NewBudgetService(budgetRepo, subRepo)
+
+	budgetRepo.On("GetByUserID", budgetUserID).Return(nil, nil)
+
+	warning, err := svc.Check(context.Background(), budgetUserID, "05-2024", 100000)
+
+	assert.NoError(t, err)
+	assert.Nil(t, warning)
+	subRepo.AssertNotCalled(t, "GetTotalPrice", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBudgetStatus_ReportsSpendAgainstLimit(t *testing.T) {
+	budgetRepo := new(MockBudgetRepository)
+	subRepo := new(MockSubscriptionRepository)
+	svc := service.NewBudgetService(budgetRepo, subRepo)
+
+	budgetRepo.On("GetByUserID", budgetUserID).Return(&model.Budget{MonthlyLimit: 1500, Enforcement: model.BudgetEnforcementWarn}, nil)
+	subRepo.On("GetTotalPrice", budgetUserID, "", "05-2024", "05-2024").Return(1200, nil)
+
+	status, err := svc.GetStatus(context.Background(), budgetUserID, "05-2024")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1200, status.Spend)
+	assert.Equal(t, 300, status.Remaining)
+	assert.False(t, status.Exceeded)
+}
+
+func TestCreateSubscription_RejectedByBudget(t *testing.T) {
+	subRepo := new(MockSubscriptionRepository)
+	budgetRepo := new(MockBudgetRepository)
+	budgets := service.NewBudgetService(budgetRepo, subRepo)
+	svc := service.NewSubscriptionService(subRepo, nil, budgets, nil)
+
+	req := &model.CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       1000,
+		UserID:      budgetUserID,
+		StartDate:   "01-2024",
+	}
+
+	budgetRepo.On("GetByUserID", budgetUserID).Return(&model.Budget{MonthlyLimit: 500, Enforcement: model.BudgetEnforcementReject}, nil)
+	subRepo.On("FindOverlapping", mock.Anything).Return(nil, nil)
+	subRepo.On("GetTotalPrice", budgetUserID, "", mock.Anything, mock.Anything).Return(0, nil)
+
+	sub, err := svc.Create(context.Background(), req)
+
+	assert.ErrorIs(t, err, service.ErrBudgetExceeded)
+	assert.Nil(t, sub)
+	subRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
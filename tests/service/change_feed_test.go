@@ -0,0 +1,120 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+)
+
+func TestChangeFeed_FiltersByUser(t *testing.T) {
+	feed := service.NewChangeFeed()
+	source := make(chan model.SubscriptionChange)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go feed.Run(ctx, source)
+
+	userA := "123e4567-e89b-12d3-a456-426614174000"
+	userB := "123e4567-e89b-12d3-a456-426614174999"
+
+	forA, stopA := feed.Subscribe("brand-a", userA)
+	defer stopA()
+	forAll, stopAll := feed.Subscribe("brand-a", "")
+	defer stopAll()
+
+	source <- model.SubscriptionChange{Event: model.EventSubscriptionCreated, ID: "sub-1", TenantID: "brand-a", UserID: userB}
+	source <- model.SubscriptionChange{Event: model.EventSubscriptionUpdated, ID: "sub-2", TenantID: "brand-a", UserID: userA}
+
+	// Подписчик без фильтра получает оба события
+	assert.Equal(t, "sub-1", (<-forAll).ID)
+	assert.Equal(t, "sub-2", (<-forAll).ID)
+
+	// Подписчик с фильтром получает только события своего пользователя
+	select {
+	case change := <-forA:
+		assert.Equal(t, "sub-2", change.ID)
+	case <-time.After(time.Second):
+		t.Fatal("change for user was not delivered")
+	}
+	assert.Len(t, forA, 0)
+}
+
+func TestChangeFeed_FiltersByTenant(t *testing.T) {
+	feed := service.NewChangeFeed()
+	source := make(chan model.SubscriptionChange)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go feed.Run(ctx, source)
+
+	changes, stop := feed.Subscribe("brand-a", "")
+	defer stop()
+
+	// События другого арендатора не доставляются
+	source <- model.SubscriptionChange{Event: model.EventSubscriptionCreated, ID: "sub-1", TenantID: "brand-b"}
+	source <- model.SubscriptionChange{Event: model.EventSubscriptionCreated, ID: "sub-2", TenantID: "brand-a"}
+
+	select {
+	case change := <-changes:
+		assert.Equal(t, "sub-2", change.ID)
+	case <-time.After(time.Second):
+		t.Fatal("change for tenant was not delivered")
+	}
+	assert.Len(t, changes, 0)
+}
+
+func TestChangeFeed_ClosesSubscribersWhenStopped(t *testing.T) {
+	feed := service.NewChangeFeed()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		feed.Run(ctx, make(chan model.SubscriptionChange))
+		close(done)
+	}()
+
+	changes, stop := feed.Subscribe("brand-a", "")
+	defer stop()
+
+	// Остановка ленты закрывает каналы открытых потоков
+	cancel()
+	<-done
+	_, ok := <-changes
+	assert.False(t, ok)
+
+	// Подписка после остановки сразу получает закрытый канал
+	late, stopLate := feed.Subscribe("brand-a", "")
+	defer stopLate()
+	_, ok = <-late
+	assert.False(t, ok)
+}
+
+func TestChangeFeed_DeliversSharedSubscriptionsToMembers(t *testing.T) {
+	feed := service.NewChangeFeed()
+	source := make(chan model.SubscriptionChange)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go feed.Run(ctx, source)
+
+	owner := "123e4567-e89b-12d3-a456-426614174000"
+	member := "123e4567-e89b-12d3-a456-426614174999"
+
+	changes, stop := feed.Subscribe("brand-a", member)
+	defer stop()
+
+	source <- model.SubscriptionChange{Event: model.EventSubscriptionCreated, ID: "sub-1", TenantID: "brand-a", UserID: owner}
+	source <- model.SubscriptionChange{Event: model.EventSubscriptionUpdated, ID: "sub-2", TenantID: "brand-a", UserID: owner, MemberIDs: []string{member}}
+
+	// Участник получает изменения только общих с ним подписок
+	select {
+	case change := <-changes:
+		assert.Equal(t, "sub-2", change.ID)
+	case <-time.After(time.Second):
+		t.Fatal("change for member was not delivered")
+	}
+	assert.Len(t, changes, 0)
+}
@@ -0,0 +1,101 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+)
+
+// Мок для репозитория ключей идемпотентности
+type MockIdempotencyRepository struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyRepository) Reserve(ctx context.Context, record *model.IdempotencyRecord, abandonAfter time.Duration) (bool, error) {
+	args := m.Called(record, abandonAfter)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) Get(ctx context.Context, scope, key string) (*model.IdempotencyRecord, error) {
+	args := m.Called(scope, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IdempotencyRecord), args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) Complete(ctx context.Context, scope, key string, response *model.StoredResponse) error {
+	args := m.Called(scope, key, response)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) Delete(ctx context.Context, scope, key string) error {
+	args := m.Called(scope, key)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestIdempotencyBegin_NewKey(t *testing.T) {
+	mockRepo := new(MockIdempotencyRepository)
+	svc := service.NewIdempotencyService(mockRepo, 24*time.Hour)
+
+	mockRepo.On("Reserve", mock.MatchedBy(func(r *model.IdempotencyRecord) bool {
+		// Ключ хранится в течение TTL
+		return r.Key == "key-1" && r.RequestHash == "hash" && time.Until(r.ExpiresAt) > 23*time.Hour
+	}), time.Minute).Return(true, nil)
+
+	stored, err := svc.Begin(context.Background(), "scope", "key-1", "hash")
+
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestIdempotencyBegin_ReplaysStoredResponse(t *testing.T) {
+	mockRepo := new(MockIdempotencyRepository)
+	svc := service.NewIdempotencyService(mockRepo, time.Hour)
+
+	status := 201
+	contentType := "application/json"
+	mockRepo.On("Reserve", mock.Anything, time.Minute).Return(false, nil)
+	mockRepo.On("Get", "scope", "key-1").Return(&model.IdempotencyRecord{
+		RequestHash:  "hash",
+		StatusCode:   &status,
+		ContentType:  &contentType,
+		ResponseBody: []byte(`{"id":"1"}`),
+	}, nil)
+
+	stored, err := svc.Begin(context.Background(), "scope", "key-1", "hash")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.StoredResponse{StatusCode: 201, ContentType: contentType, Body: []byte(`{"id":"1"}`)}, stored)
+}
+
+func TestIdempotencyBegin_Conflicts(t *testing.T) {
+	mockRepo := new(MockIdempotencyRepository)
+	svc := service.NewIdempotencyService(mockRepo, time.Hour)
+
+	mockRepo.On("Reserve", mock.Anything, time.Minute).Return(false, nil)
+	mockRepo.On("Get", "scope", "key-1").Return(&model.IdempotencyRecord{RequestHash: "hash"}, nil)
+
+	// Тот же ключ с другим телом запроса
+	_, err := svc.Begin(context.Background(), "scope", "key-1", "other-hash")
+	assert.ErrorIs(t, err, service.ErrIdempotencyKeyReused)
+
+	// Первый запрос ещё не завершён
+	_, err = svc.Begin(context.Background(), "scope", "key-1", "hash")
+	assert.ErrorIs(t, err, service.ErrIdempotencyKeyInProgress)
+
+	_, err = svc.Begin(context.Background(), "scope", strings.Repeat("k", 256), "hash")
+	assert.ErrorIs(t, err, service.ErrInvalidIdempotencyKey)
+}
@@ -0,0 +1,91 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+)
+
+// Мок для репозитория outbox: вызывает publish для заданных событий
+type MockOutboxRepository struct {
+	mock.Mock
+	events []model.OutboxEvent
+}
+
+func (m *MockOutboxRepository) Relay(ctx context.Context, limit int, publish func(event model.OutboxEvent) error) (int, error) {
+	m.Called(limit)
+	published := 0
+	for _, event := range m.events {
+		if err := publish(event); err != nil {
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
+
+func TestOutboxRelay_PublishesToHTTPStub(t *testing.T) {
+	var received []model.OutboxEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event model.OutboxEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = append(received, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	mockRepo := &MockOutboxRepository{events: []model.OutboxEvent{
+		{ID: 1, EventType: model.EventSubscriptionCreated, Payload: json.RawMessage(`{"id":"sub-1"}`)},
+		{ID: 2, EventType: model.EventSubscriptionDeleted, Payload: json.RawMessage(`{"id":"sub-1"}`)},
+	}}
+	mockRepo.On("Relay", 10)
+
+	relay := service.NewOutboxRelay(mockRepo, service.NewHTTPPublisher(server.URL, time.Second), time.Second, 10)
+	published, err := relay.RelayBatch(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Len(t, received, 2)
+	assert.Equal(t, model.EventSubscriptionDeleted, received[1].EventType)
+}
+
+func TestOutboxRelay_HTTPErrorStopsBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	mockRepo := &MockOutboxRepository{events: []model.OutboxEvent{{ID: 1}, {ID: 2}}}
+	mockRepo.On("Relay", 10)
+
+	relay := service.NewOutboxRelay(mockRepo, service.NewHTTPPublisher(server.URL, time.Second), time.Second, 10)
+	published, err := relay.RelayBatch(context.Background())
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, published)
+}
+
+func TestWriterPublisher_WritesJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	publisher := service.NewWriterPublisher(&buf)
+
+	err := publisher.Publish(context.Background(), model.OutboxEvent{ID: 7, EventType: model.EventSubscriptionUpdated, Payload: json.RawMessage(`{}`)})
+
+	assert.NoError(t, err)
+	var event model.OutboxEvent
+	assert.NoError(t, json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &event))
+	assert.Equal(t, int64(7), event.ID)
+	assert.True(t, bytes.HasSuffix(buf.Bytes(), []byte("\n")))
+}
@@ -1,11 +1,16 @@
 package service_test
 
 import (
+	"context"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/t5129001t-jpg/subscription-service/internal/authz"
 	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
 	"github.com/t5129001t-jpg/subscription-service/internal/service"
 )
 
@@ -14,12 +19,12 @@ type MockSubscriptionRepository struct {
 	mock.Mock
 }
 
-func (m *MockSubscriptionRepository) Create(sub *model.Subscription) error {
+func (m *MockSubscriptionRepository) Create(ctx context.Context, sub *model.Subscription) error {
 	args := m.Called(sub)
 	return args.Error(0)
 }
 
-func (m *MockSubscriptionRepository) GetByID(id string) (*model.Subscription, error) {
+func (m *MockSubscriptionRepository) GetByID(ctx context.Context, id string) (*model.Subscription, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -27,30 +32,127 @@ func (m *MockSubscriptionRepository) GetByID(id string) (*model.Subscription, er
 	return args.Get(0).(*model.Subscription), args.Error(1)
 }
 
-func (m *MockSubscriptionRepository) Update(id string, updates map[string]interface{}) error {
+func (m *MockSubscriptionRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
 	args := m.Called(id, updates)
 	return args.Error(0)
 }
 
-func (m *MockSubscriptionRepository) Delete(id string) error {
+func (m *MockSubscriptionRepository) Delete(ctx context.Context, id string) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockSubscriptionRepository) List(filter model.SubscriptionFilter) ([]model.Subscription, int, error) {
+func (m *MockSubscriptionRepository) GetDeleted(ctx context.Context, id string) (*model.Subscription, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) Restore(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockSubscriptionRepository) List(ctx context.Context, filter model.SubscriptionFilter) ([]model.Subscription, int, error) {
 	args := m.Called(filter)
 	return args.Get(0).([]model.Subscription), args.Int(1), args.Error(2)
 }
 
-func (m *MockSubscriptionRepository) GetTotalPrice(userID, serviceName, startMonth, endMonth string) (int, error) {
+func (m *MockSubscriptionRepository) GetTotalPrice(ctx context.Context, userID, serviceName, startMonth, endMonth string) (int, error) {
 	args := m.Called(userID, serviceName, startMonth, endMonth)
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockSubscriptionRepository) CreatePause(ctx context.Context, subscriptionID, startMonth string) (*model.SubscriptionPause, error) {
+	args := m.Called(subscriptionID, startMonth)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.SubscriptionPause), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) GetOpenPause(ctx context.Context, subscriptionID string) (*model.SubscriptionPause, error) {
+	args := m.Called(subscriptionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.SubscriptionPause), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) ClosePause(ctx context.Context, pauseID, endMonth string) error {
+	args := m.Called(pauseID, endMonth)
+	return args.Error(0)
+}
+
+func (m *MockSubscriptionRepository) DeletePause(ctx context.Context, pauseID string) error {
+	args := m.Called(pauseID)
+	return args.Error(0)
+}
+
+func (m *MockSubscriptionRepository) ListPauses(ctx context.Context, subscriptionID string) ([]model.SubscriptionPause, error) {
+	args := m.Called(subscriptionID)
+	return args.Get(0).([]model.SubscriptionPause), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) ListPausesFor(ctx context.Context, subscriptionIDs []string) (map[string][]model.SubscriptionPause, error) {
+	args := m.Called(subscriptionIDs)
+	return args.Get(0).(map[string][]model.SubscriptionPause), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) ListByUser(ctx context.Context, userID string) ([]model.Subscription, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]model.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) ListBillable(ctx context.Context) ([]model.Subscription, error) {
+	args := m.Called()
+	return args.Get(0).([]model.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) FindOverlapping(ctx context.Context, sub *model.Subscription) (*model.Subscription, error) {
+	args := m.Called(sub)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) ListOverlaps(ctx context.Context, userID string) ([]model.SubscriptionOverlap, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]model.SubscriptionOverlap), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) ListMembers(ctx context.Context, subscriptionID string) ([]model.SubscriptionMember, error) {
+	args := m.Called(subscriptionID)
+	return args.Get(0).([]model.SubscriptionMember), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) SetMember(ctx context.Context, member *model.SubscriptionMember) error {
+	args := m.Called(member)
+	return args.Error(0)
+}
+
+func (m *MockSubscriptionRepository) DeleteMember(ctx context.Context, subscriptionID, userID string) error {
+	args := m.Called(subscriptionID, userID)
+	return args.Error(0)
+}
+
+// Мок для публикации событий
+type MockEventPublisher struct {
+	mock.Mock
+}
+
+func (m *MockEventPublisher) Publish(ctx context.Context, eventType string, data interface{}) error {
+	args := m.Called(eventType, data)
+	return args.Error(0)
+}
+
 // Тесты для сервиса
 func TestCreateSubscription_ValidData(t *testing.T) {
 	mockRepo := new(MockSubscriptionRepository)
-	svc := service.NewSubscriptionService(mockRepo)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
 
 	req := &model.CreateSubscriptionRequest{
 		ServiceName: "Netflix",
@@ -66,9 +168,10 @@ func TestCreateSubscription_ValidData(t *testing.T) {
 		StartDate:   req.StartDate,
 	}
 
+	mockRepo.On("FindOverlapping", mock.AnythingOfType("*model.Subscription")).Return(nil, nil)
 	mockRepo.On("Create", mock.AnythingOfType("*model.Subscription")).Return(nil)
 
-	sub, err := svc.Create(req)
+	sub, err := svc.Create(context.Background(), req)
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedSub.ServiceName, sub.ServiceName)
@@ -78,7 +181,7 @@ func TestCreateSubscription_ValidData(t *testing.T) {
 
 func TestCreateSubscription_InvalidDate(t *testing.T) {
 	mockRepo := new(MockSubscriptionRepository)
-	svc := service.NewSubscriptionService(mockRepo)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
 
 	req := &model.CreateSubscriptionRequest{
 		ServiceName: "Netflix",
@@ -87,9 +190,624 @@ func TestCreateSubscription_InvalidDate(t *testing.T) {
 		StartDate:   "13-2024", // Неверный месяц
 	}
 
-	sub, err := svc.Create(req)
+	sub, err := svc.Create(context.Background(), req)
 
 	assert.Error(t, err)
 	assert.Nil(t, sub)
 	assert.Contains(t, err.Error(), "invalid start_date format")
 }
+
+func TestPauseSubscription_CreatesPause(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	month := "03-2024"
+	active := &model.Subscription{ID: id, StartDate: "01-2024", Status: model.SubscriptionStatusActive}
+	paused := &model.Subscription{ID: id, StartDate: "01-2024", Status: model.SubscriptionStatusPaused}
+
+	mockRepo.On("GetByID", id).Return(active, nil).Once()
+	mockRepo.On("GetOpenPause", id).Return(nil, nil)
+	mockRepo.On("CreatePause", id, month).Return(&model.SubscriptionPause{ID: "pause-id"}, nil)
+	mockRepo.On("GetByID", id).Return(paused, nil).Once()
+
+	sub, err := svc.Pause(context.Background(), id, &model.PauseSubscriptionRequest{Month: &month})
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusPaused, sub.Status)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPauseSubscription_AlreadyPaused(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	month := "03-2024"
+	sub := &model.Subscription{ID: id, StartDate: "01-2024", Status: model.SubscriptionStatusPaused}
+
+	mockRepo.On("GetByID", id).Return(sub, nil)
+	mockRepo.On("GetOpenPause", id).Return(&model.SubscriptionPause{ID: "pause-id", StartMonth: "02-2024"}, nil)
+
+	_, err := svc.Pause(context.Background(), id, &model.PauseSubscriptionRequest{Month: &month})
+
+	assert.ErrorIs(t, err, service.ErrAlreadyPaused)
+	mockRepo.AssertNotCalled(t, "CreatePause", id, month)
+}
+
+func TestResumeSubscription_ClosesPauseBeforeResumeMonth(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	month := "01-2025"
+	sub := &model.Subscription{ID: id, StartDate: "01-2024"}
+
+	mockRepo.On("GetByID", id).Return(sub, nil)
+	mockRepo.On("GetOpenPause", id).Return(&model.SubscriptionPause{ID: "pause-id", StartMonth: "10-2024"}, nil)
+	// Пауза длится до месяца, предшествующего возобновлению
+	mockRepo.On("ClosePause", "pause-id", "12-2024").Return(nil)
+
+	_, err := svc.Resume(context.Background(), id, &model.ResumeSubscriptionRequest{Month: &month})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPauseSubscription_ConcurrentPauseConflicts(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	month := "03-2024"
+	sub := &model.Subscription{ID: id, StartDate: "01-2024", Status: model.SubscriptionStatusActive}
+
+	mockRepo.On("GetByID", id).Return(sub, nil)
+	mockRepo.On("GetOpenPause", id).Return(nil, nil)
+	// Параллельный запрос успел открыть паузу — уникальный индекс отвечает конфликтом
+	mockRepo.On("CreatePause", id, month).Return(nil, repository.ErrConflict)
+
+	_, err := svc.Pause(context.Background(), id, &model.PauseSubscriptionRequest{Month: &month})
+
+	assert.ErrorIs(t, err, service.ErrAlreadyPaused)
+}
+
+func TestResumeSubscription_BeforePauseStart(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	month := "09-2024"
+	sub := &model.Subscription{ID: id, StartDate: "01-2024"}
+
+	mockRepo.On("GetByID", id).Return(sub, nil)
+	mockRepo.On("GetOpenPause", id).Return(&model.SubscriptionPause{ID: "pause-id", StartMonth: "10-2024"}, nil)
+
+	// Возобновить раньше начала паузы нельзя, и пауза не теряется
+	_, err := svc.Resume(context.Background(), id, &model.ResumeSubscriptionRequest{Month: &month})
+
+	var validationErr *service.ValidationError
+	if assert.ErrorAs(t, err, &validationErr) {
+		assert.Equal(t, "month", validationErr.Field)
+	}
+	mockRepo.AssertNotCalled(t, "DeletePause", mock.Anything)
+}
+
+func TestResumeSubscription_NotPaused(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	sub := &model.Subscription{ID: id, StartDate: "01-2024"}
+
+	mockRepo.On("GetByID", id).Return(sub, nil)
+	mockRepo.On("GetOpenPause", id).Return(nil, nil)
+
+	_, err := svc.Resume(context.Background(), id, &model.ResumeSubscriptionRequest{})
+
+	assert.ErrorIs(t, err, service.ErrNotPaused)
+}
+
+func TestCreateSubscription_WithTrialStartsInTrial(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	trialEnd := "02-2024"
+	req := &model.CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       1000,
+		UserID:      "123e4567-e89b-12d3-a456-426614174000",
+		StartDate:   "01-2024",
+		TrialEnd:    &trialEnd,
+	}
+
+	mockRepo.On("FindOverlapping", mock.AnythingOfType("*model.Subscription")).Return(nil, nil)
+	mockRepo.On("Create", mock.AnythingOfType("*model.Subscription")).Return(nil)
+
+	sub, err := svc.Create(context.Background(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusTrial, sub.Status)
+	assert.Equal(t, &trialEnd, sub.TrialEnd)
+}
+
+func TestChangeStatus_InvalidTransition(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	sub := &model.Subscription{ID: id, StartDate: "01-2024", Status: model.SubscriptionStatusCancelled}
+
+	mockRepo.On("GetByID", id).Return(sub, nil)
+
+	_, err := svc.ChangeStatus(context.Background(), id, &model.ChangeStatusRequest{Status: model.SubscriptionStatusActive})
+
+	assert.ErrorIs(t, err, service.ErrInvalidTransition)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestChangeStatus_CancelSetsEndDate(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	sub := &model.Subscription{ID: id, StartDate: "01-2024", Status: model.SubscriptionStatusActive}
+
+	mockRepo.On("GetByID", id).Return(sub, nil)
+	mockRepo.On("Update", id, mock.MatchedBy(func(updates map[string]interface{}) bool {
+		return updates["status"] == model.SubscriptionStatusCancelled && updates["end_date"] != nil
+	})).Return(nil)
+
+	_, err := svc.ChangeStatus(context.Background(), id, &model.ChangeStatusRequest{Status: model.SubscriptionStatusCancelled})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPauseSubscription_TrialCannotBePaused(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	sub := &model.Subscription{ID: id, StartDate: "01-2024", Status: model.SubscriptionStatusTrial}
+
+	mockRepo.On("GetByID", id).Return(sub, nil)
+
+	_, err := svc.Pause(context.Background(), id, &model.PauseSubscriptionRequest{})
+
+	assert.ErrorIs(t, err, service.ErrInvalidTransition)
+}
+
+func TestCancelSubscription_WithEffectiveMonthAndReason(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	month := "06-2024"
+	reason := "too expensive"
+	sub := &model.Subscription{ID: id, StartDate: "01-2024", Status: model.SubscriptionStatusActive}
+
+	mockRepo.On("GetByID", id).Return(sub, nil)
+	mockRepo.On("Update", id, mock.MatchedBy(func(updates map[string]interface{}) bool {
+		return updates["status"] == model.SubscriptionStatusCancelled &&
+			updates["end_date"] == month &&
+			updates["cancellation_reason"] == reason
+	})).Return(nil)
+
+	_, err := svc.Cancel(context.Background(), id, &model.CancelSubscriptionRequest{EffectiveMonth: &month, Reason: &reason})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCancelSubscription_EffectiveMonthBeforeStart(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	month := "12-2023"
+	sub := &model.Subscription{ID: id, StartDate: "01-2024", Status: model.SubscriptionStatusActive}
+
+	mockRepo.On("GetByID", id).Return(sub, nil)
+
+	_, err := svc.Cancel(context.Background(), id, &model.CancelSubscriptionRequest{EffectiveMonth: &month})
+
+	var validationErr *service.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "effective_month", validationErr.Field)
+}
+
+func TestUpcomingRenewals_SortedWithinWindow(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	userID := "123e4567-e89b-12d3-a456-426614174000"
+	trialEnd := "12-2099"
+	subscriptions := []model.Subscription{
+		{ID: "a", ServiceName: "Netflix", Price: 1000, StartDate: "01-2020", BillingDay: 28, Status: model.SubscriptionStatusActive},
+		{ID: "b", ServiceName: "Spotify", Price: 300, StartDate: "01-2020", BillingDay: 1, Status: model.SubscriptionStatusActive},
+		// Пробный период не тарифицируется
+		{ID: "c", ServiceName: "Yandex", Price: 500, StartDate: "01-2020", TrialEnd: &trialEnd, BillingDay: 1, Status: model.SubscriptionStatusTrial},
+	}
+
+	// Паузы всех подписок читаются одним запросом; Spotify на паузе
+	pauses := map[string][]model.SubscriptionPause{
+		"b": {{SubscriptionID: "b", StartMonth: "01-2020"}},
+	}
+	mockRepo.On("ListByUser", userID).Return(subscriptions, nil)
+	mockRepo.On("ListPausesFor", []string{"a", "b", "c"}).Return(pauses, nil).Once()
+
+	renewals, err := svc.UpcomingRenewals(context.Background(), userID, 62)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "ListPauses", mock.Anything)
+	assert.GreaterOrEqual(t, len(renewals), 1)
+	assert.True(t, sort.SliceIsSorted(renewals, func(i, j int) bool {
+		return renewals[i].ChargeDate < renewals[j].ChargeDate
+	}))
+
+	today := time.Now().Format("2006-01-02")
+	until := time.Now().AddDate(0, 0, 62).Format("2006-01-02")
+	for _, renewal := range renewals {
+		assert.Equal(t, "a", renewal.SubscriptionID)
+		assert.GreaterOrEqual(t, renewal.ChargeDate, today)
+		assert.LessOrEqual(t, renewal.ChargeDate, until)
+	}
+}
+
+func TestUpcomingRenewals_InvalidWindow(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	_, err := svc.UpcomingRenewals(context.Background(), "123e4567-e89b-12d3-a456-426614174000", 0)
+
+	var validationErr *service.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+}
+
+func TestCreateSubscription_PublishesCreatedEvent(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	mockEvents := new(MockEventPublisher)
+	svc := service.NewSubscriptionService(mockRepo, mockEvents, nil, nil)
+
+	req := &model.CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       1000,
+		UserID:      "123e4567-e89b-12d3-a456-426614174000",
+		StartDate:   "01-2024",
+	}
+
+	mockRepo.On("FindOverlapping", mock.AnythingOfType("*model.Subscription")).Return(nil, nil)
+	mockRepo.On("Create", mock.AnythingOfType("*model.Subscription")).Return(nil)
+	mockEvents.On("Publish", model.EventSubscriptionCreated, mock.AnythingOfType("*model.Subscription")).Return(nil)
+
+	_, err := svc.Create(context.Background(), req)
+
+	assert.NoError(t, err)
+	mockEvents.AssertExpectations(t)
+}
+
+func TestCreateSubscription_OverlapRejected(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	req := &model.CreateSubscriptionRequest{
+		ServiceName: "netflix ",
+		Price:       1000,
+		UserID:      "123e4567-e89b-12d3-a456-426614174000",
+		StartDate:   "03-2024",
+	}
+
+	existing := &model.Subscription{ID: "123e4567-e89b-12d3-a456-426614174001", ServiceName: "Netflix"}
+	mockRepo.On("FindOverlapping", mock.AnythingOfType("*model.Subscription")).Return(existing, nil)
+
+	sub, err := svc.Create(context.Background(), req)
+
+	var duplicateErr *service.DuplicateSubscriptionError
+	assert.ErrorAs(t, err, &duplicateErr)
+	assert.ErrorIs(t, err, service.ErrDuplicateSubscription)
+	assert.Equal(t, existing.ID, duplicateErr.ConflictingID)
+	assert.Nil(t, sub)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestCreateSubscription_AllowOverlapSkipsCheck(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	req := &model.CreateSubscriptionRequest{
+		ServiceName:  "Netflix",
+		Price:        1000,
+		UserID:       "123e4567-e89b-12d3-a456-426614174000",
+		StartDate:    "03-2024",
+		AllowOverlap: true,
+	}
+
+	mockRepo.On("Create", mock.AnythingOfType("*model.Subscription")).Return(nil)
+
+	_, err := svc.Create(context.Background(), req)
+
+	assert.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "FindOverlapping", mock.Anything)
+}
+
+func TestSubscriptionService_ClientErrorsAreValidationErrors(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+	ctx := context.Background()
+
+	end := "01-2024"
+	_, createErr := svc.Create(ctx, &model.CreateSubscriptionRequest{
+		ServiceName: "Netflix", Price: 1000, UserID: "123e4567-e89b-12d3-a456-426614174000", StartDate: "02-2024", EndDate: &end,
+	})
+	_, monthErr := svc.GetTotalPrice(ctx, model.SubscriptionFilter{Month: "2024-01"})
+	_, rangeErr := svc.GetTotalPrice(ctx, model.SubscriptionFilter{StartMonth: "01-2024"})
+
+	// Ошибки клиента называют поле, чтобы обработчик ответил 400, а не 500
+	for field, err := range map[string]error{"end_date": createErr, "month": monthErr, "start_month": rangeErr} {
+		var validationErr *service.ValidationError
+		if assert.ErrorAs(t, err, &validationErr) {
+			assert.Equal(t, field, validationErr.Field)
+		}
+	}
+}
+
+func TestUpdateSubscription_OverlapExcludesItself(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	start := "02-2024"
+	existing := &model.Subscription{ID: id, ServiceName: "Netflix", UserID: "123e4567-e89b-12d3-a456-426614174000", StartDate: "01-2024"}
+
+	mockRepo.On("GetByID", id).Return(existing, nil)
+	mockRepo.On("FindOverlapping", mock.MatchedBy(func(sub *model.Subscription) bool {
+		return sub.ID == id && sub.StartDate == start
+	})).Return(nil, nil)
+	mockRepo.On("Update", id, map[string]interface{}{"start_date": start}).Return(nil)
+
+	_, err := svc.Update(context.Background(), id, &model.UpdateSubscriptionRequest{StartDate: &start})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateSubscription_TrialEndChecksMergedRow(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	end := "06-2024"
+	existing := &model.Subscription{
+		ID: id, UserID: "123e4567-e89b-12d3-a456-426614174000",
+		StartDate: "03-2024", EndDate: &end, Status: model.SubscriptionStatusTrial,
+	}
+	mockRepo.On("GetByID", id).Return(existing, nil)
+
+	// Конец пробного периода сверяется с датами, которые уже сохранены
+	early, late := "01-2024", "09-2024"
+	_, earlyErr := svc.Update(context.Background(), id, &model.UpdateSubscriptionRequest{TrialEnd: &early})
+	_, lateErr := svc.Update(context.Background(), id, &model.UpdateSubscriptionRequest{TrialEnd: &late})
+
+	for _, err := range []error{earlyErr, lateErr} {
+		var validationErr *service.ValidationError
+		if assert.ErrorAs(t, err, &validationErr) {
+			assert.Equal(t, "trial_end", validationErr.Field)
+		}
+	}
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestUpdateSubscription_TrialEndOnlyDuringTrial(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	existing := &model.Subscription{
+		ID: id, UserID: "123e4567-e89b-12d3-a456-426614174000",
+		StartDate: "03-2024", Status: model.SubscriptionStatusActive,
+	}
+	mockRepo.On("GetByID", id).Return(existing, nil)
+
+	// Активной подписке пробный период задним числом не назначить
+	trialEnd := "04-2024"
+	_, err := svc.Update(context.Background(), id, &model.UpdateSubscriptionRequest{TrialEnd: &trialEnd})
+
+	assert.ErrorIs(t, err, service.ErrInvalidTransition)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestUpdateSubscription_PriceBelowMembersShares(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	existing := &model.Subscription{ID: id, Price: 1000, UserID: "123e4567-e89b-12d3-a456-426614174000"}
+	members := []model.SubscriptionMember{
+		{SubscriptionID: id, UserID: "123e4567-e89b-12d3-a456-426614174002", ShareType: model.ShareTypeFixed, ShareValue: 400},
+		{SubscriptionID: id, UserID: "123e4567-e89b-12d3-a456-426614174003", ShareType: model.ShareTypeFixed, ShareValue: 300},
+	}
+
+	mockRepo.On("GetByID", id).Return(existing, nil)
+	mockRepo.On("ListMembers", id).Return(members, nil)
+
+	// Участники платят 700 фиксированно — цена 600 им не покрывается
+	price := 600
+	_, err := svc.Update(context.Background(), id, &model.UpdateSubscriptionRequest{Price: &price})
+
+	var validationErr *service.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "price", validationErr.Field)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestSetMember_SharesWithinPrice(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	memberID := "123e4567-e89b-12d3-a456-426614174002"
+	existing := &model.Subscription{ID: id, Price: 1000, UserID: "123e4567-e89b-12d3-a456-426614174000"}
+	others := []model.SubscriptionMember{
+		{SubscriptionID: id, UserID: "123e4567-e89b-12d3-a456-426614174003", ShareType: model.ShareTypeFixed, ShareValue: 300},
+	}
+
+	mockRepo.On("GetByID", id).Return(existing, nil)
+	mockRepo.On("ListMembers", id).Return(others, nil)
+	mockRepo.On("SetMember", mock.MatchedBy(func(member *model.SubscriptionMember) bool {
+		return member.UserID == memberID && member.ShareType == model.ShareTypePercent && member.ShareValue == 50
+	})).Return(nil)
+
+	value := 50
+	member, err := svc.SetMember(context.Background(), id, memberID, &model.SetMemberRequest{ShareType: model.ShareTypePercent, ShareValue: &value})
+
+	assert.NoError(t, err)
+	// 50% от 1000 — участник платит 500, владелец оставшиеся 200
+	assert.Equal(t, 500, member.Amount(existing.Price))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSetMember_SharesExceedPrice(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	existing := &model.Subscription{ID: id, Price: 1000, UserID: "123e4567-e89b-12d3-a456-426614174000"}
+	others := []model.SubscriptionMember{
+		{SubscriptionID: id, UserID: "123e4567-e89b-12d3-a456-426614174003", ShareType: model.ShareTypePercent, ShareValue: 60},
+	}
+
+	mockRepo.On("GetByID", id).Return(existing, nil)
+	mockRepo.On("ListMembers", id).Return(others, nil)
+
+	value := 500
+	_, err := svc.SetMember(context.Background(), id, "123e4567-e89b-12d3-a456-426614174002", &model.SetMemberRequest{ShareType: model.ShareTypeFixed, ShareValue: &value})
+
+	var validationErr *service.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	mockRepo.AssertNotCalled(t, "SetMember", mock.Anything)
+}
+
+func TestRemoveMember_NotMember(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	mockRepo.On("GetByID", id).Return(&model.Subscription{ID: id}, nil)
+	mockRepo.On("ListMembers", id).Return([]model.SubscriptionMember{}, nil)
+
+	err := svc.RemoveMember(context.Background(), id, "123e4567-e89b-12d3-a456-426614174002")
+
+	assert.ErrorIs(t, err, service.ErrMemberNotFound)
+	mockRepo.AssertNotCalled(t, "DeleteMember", mock.Anything, mock.Anything)
+}
+
+func TestCreateSubscription_UnknownUser(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, nil)
+
+	req := &model.CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       1000,
+		UserID:      "123e4567-e89b-12d3-a456-426614174000",
+		StartDate:   "03-2024",
+	}
+
+	mockRepo.On("FindOverlapping", mock.AnythingOfType("*model.Subscription")).Return(nil, nil)
+	mockRepo.On("Create", mock.AnythingOfType("*model.Subscription")).Return(repository.ErrMissingReference)
+
+	_, err := svc.Create(context.Background(), req)
+
+	var validationErr *service.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "user_id", validationErr.Field)
+}
+
+func TestDeleteSubscription_ForbiddenForOtherUser(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, authz.DefaultPolicy())
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	mockRepo.On("GetByID", id).Return(&model.Subscription{ID: id, UserID: "123e4567-e89b-12d3-a456-426614174000"}, nil)
+
+	// Пользователь не может удалить чужую подписку
+	ctx := authz.WithPrincipal(context.Background(), &model.Principal{Subject: "123e4567-e89b-12d3-a456-426614174002"})
+	err := svc.Delete(ctx, id)
+
+	assert.ErrorIs(t, err, authz.ErrForbidden)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything)
+}
+
+func TestWriteSubscription_MissingIsNotFound(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, authz.DefaultPolicy())
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	mockRepo.On("GetByID", id).Return(nil, nil)
+
+	// Несуществующая подписка — 404 до записи и без пропуска авторизации
+	ctx := authz.WithPrincipal(context.Background(), &model.Principal{Subject: "123e4567-e89b-12d3-a456-426614174002"})
+	price := 500
+	_, updateErr := svc.Update(ctx, id, &model.UpdateSubscriptionRequest{Price: &price})
+	deleteErr := svc.Delete(ctx, id)
+
+	assert.ErrorIs(t, updateErr, service.ErrSubscriptionNotFound)
+	assert.ErrorIs(t, deleteErr, service.ErrSubscriptionNotFound)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything)
+}
+
+func TestRestoreSubscription_BySupport(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, authz.DefaultPolicy())
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	deleted := &model.Subscription{ID: id, UserID: "123e4567-e89b-12d3-a456-426614174000", ServiceName: "Netflix", StartDate: "01-2024"}
+	mockRepo.On("GetDeleted", id).Return(deleted, nil)
+	mockRepo.On("FindOverlapping", deleted).Return(nil, nil)
+	mockRepo.On("Restore", id).Return(nil)
+	mockRepo.On("GetByID", id).Return(deleted, nil)
+
+	ctx := authz.WithPrincipal(context.Background(), &model.Principal{Subject: "support-agent", Roles: []string{authz.RoleSupport}})
+	sub, err := svc.Restore(ctx, id)
+
+	assert.NoError(t, err)
+	assert.Equal(t, id, sub.ID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRestoreSubscription_ForbiddenForOwner(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, authz.DefaultPolicy())
+
+	id := "123e4567-e89b-12d3-a456-426614174001"
+	userID := "123e4567-e89b-12d3-a456-426614174000"
+	mockRepo.On("GetDeleted", id).Return(&model.Subscription{ID: id, UserID: userID}, nil)
+
+	// Восстанавливать удалённые подписки может только поддержка
+	ctx := authz.WithPrincipal(context.Background(), &model.Principal{Subject: userID})
+	_, err := svc.Restore(ctx, id)
+
+	assert.ErrorIs(t, err, authz.ErrForbidden)
+	mockRepo.AssertNotCalled(t, "Restore", mock.Anything)
+}
+
+func TestListSubscriptions_ScopedToCaller(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewSubscriptionService(mockRepo, nil, nil, authz.DefaultPolicy())
+
+	userID := "123e4567-e89b-12d3-a456-426614174000"
+	mockRepo.On("List", mock.MatchedBy(func(filter model.SubscriptionFilter) bool {
+		return filter.UserID == userID
+	})).Return([]model.Subscription{}, 0, nil)
+
+	// Без user_id пользователь видит только свои подписки
+	ctx := authz.WithPrincipal(context.Background(), &model.Principal{Subject: userID})
+	_, _, err := svc.List(ctx, model.SubscriptionFilter{})
+	assert.NoError(t, err)
+
+	_, _, err = svc.List(ctx, model.SubscriptionFilter{UserID: "123e4567-e89b-12d3-a456-426614174002"})
+	assert.ErrorIs(t, err, authz.ErrForbidden)
+	mockRepo.AssertNumberOfCalls(t, "List", 1)
+}
@@ -0,0 +1,70 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans направляет спаны в память на время теста
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracedSubscriptionService_ListRecordsFilterCombination(t *testing.T) {
+	recorder := recordSpans(t)
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewTracedSubscriptionService(service.NewSubscriptionService(mockRepo, nil, nil, nil))
+
+	mockRepo.On("List", mock.Anything).Return([]model.Subscription{}, 0, nil)
+
+	_, _, err := svc.List(context.Background(), model.SubscriptionFilter{Status: "active", ServiceName: "Netflix", Limit: 20})
+	assert.NoError(t, err)
+
+	// В спане видно, какие фильтры заданы, но не их значения
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "SubscriptionService.List", spans[0].Name())
+	assert.Equal(t, "service_name,status", spanAttribute(spans[0], "subscription.filter").AsString())
+	assert.Equal(t, int64(20), spanAttribute(spans[0], "subscription.filter.limit").AsInt64())
+}
+
+func TestTracedSubscriptionService_RecordsErrors(t *testing.T) {
+	recorder := recordSpans(t)
+	mockRepo := new(MockSubscriptionRepository)
+	svc := service.NewTracedSubscriptionService(service.NewSubscriptionService(mockRepo, nil, nil, nil))
+
+	id := "123e4567-e89b-12d3-a456-426614174000"
+	mockRepo.On("GetByID", id).Return(nil, errors.New("connection reset"))
+
+	_, err := svc.GetByID(context.Background(), id)
+	assert.Error(t, err)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, id, spanAttribute(spans[0], "subscription.id").AsString())
+}
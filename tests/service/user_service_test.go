@@ -0,0 +1,128 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+)
+
+// Мок для репозитория пользователей
+type MockUserRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserRepository) Create(ctx context.Context, user *model.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+func (m *MockUserRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	args := m.Called(id, updates)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) List(ctx context.Context, limit, offset int) ([]model.User, int, error) {
+	args := m.Called(limit, offset)
+	return args.Get(0).([]model.User), args.Int(1), args.Error(2)
+}
+
+func (m *MockUserRepository) GetSummary(ctx context.Context, id string) (*model.UserSummary, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UserSummary), args.Error(1)
+}
+
+const userID = "123e4567-e89b-12d3-a456-426614174000"
+
+func TestCreateUser_KeepsRequestedID(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	svc := service.NewUserService(mockRepo, nil)
+
+	id := userID
+	mockRepo.On("Create", mock.MatchedBy(func(user *model.User) bool {
+		return user.ID == userID && user.Name == "Alice"
+	})).Return(nil)
+
+	user, err := svc.Create(context.Background(), &model.CreateUserRequest{ID: &id, Name: "Alice"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, userID, user.ID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateUser_Conflict(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	svc := service.NewUserService(mockRepo, nil)
+
+	mockRepo.On("Create", mock.AnythingOfType("*model.User")).Return(repository.ErrConflict)
+
+	_, err := svc.Create(context.Background(), &model.CreateUserRequest{Name: "Alice"})
+
+	assert.ErrorIs(t, err, service.ErrUserExists)
+}
+
+func TestDeleteUser_WithSubscriptions(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	svc := service.NewUserService(mockRepo, nil)
+
+	mockRepo.On("GetByID", userID).Return(&model.User{ID: userID}, nil)
+	mockRepo.On("Delete", userID).Return(repository.ErrReferenced)
+
+	err := svc.Delete(context.Background(), userID)
+
+	assert.ErrorIs(t, err, service.ErrUserHasSubscriptions)
+}
+
+func TestGetUserSummary_NotFound(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	svc := service.NewUserService(mockRepo, nil)
+
+	mockRepo.On("GetSummary", userID).Return(nil, nil)
+
+	_, err := svc.GetSummary(context.Background(), userID)
+
+	assert.ErrorIs(t, err, service.ErrUserNotFound)
+}
+
+func TestEraseUserData_InvalidMode(t *testing.T) {
+	svc := service.NewUserService(new(MockUserRepository), nil)
+
+	_, err := svc.EraseData(context.Background(), userID, "shred")
+
+	var validationErr *service.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "mode", validationErr.Field)
+}
+
+func TestListUsers_CapsLimit(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	svc := service.NewUserService(mockRepo, nil)
+
+	// Не больше 100 пользователей за запрос, отрицательное смещение — с начала
+	mockRepo.On("List", 100, 0).Return([]model.User{}, 0, nil)
+
+	_, _, err := svc.List(context.Background(), 10000, -5)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
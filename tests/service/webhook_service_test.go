@@ -0,0 +1,311 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/t5129001t-jpg/subscription-service/internal/config"
+	"github.com/t5129001t-jpg/subscription-service/internal/model"
+	"github.com/t5129001t-jpg/subscription-service/internal/repository"
+	"github.com/t5129001t-jpg/subscription-service/internal/service"
+	"github.com/t5129001t-jpg/subscription-service/internal/tenant"
+)
+
+// Мок для репозитория вебхуков
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepository) Create(ctx context.Context, webhook *model.Webhook) error {
+	args := m.Called(webhook)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) GetByID(ctx context.Context, id string) (*model.Webhook, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) List(ctx context.Context) ([]model.Webhook, error) {
+	args := m.Called()
+	return args.Get(0).([]model.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) ListByEventType(ctx context.Context, tenantID, eventType string) ([]model.Webhook, error) {
+	args := m.Called(tenantID, eventType)
+	return args.Get(0).([]model.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) EnqueueDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	args := m.Called(delivery)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]model.WebhookDelivery, error) {
+	args := m.Called(webhookID, limit)
+	return args.Get(0).([]model.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]model.WebhookDelivery, error) {
+	args := m.Called(limit, lease)
+	return args.Get(0).([]model.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) HoldsLease(ctx context.Context, id string, leaseToken *string) (bool, error) {
+	args := m.Called(id, leaseToken)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWebhookRepository) MarkDelivered(ctx context.Context, id string, leaseToken *string, attempts int) error {
+	args := m.Called(id, leaseToken, attempts)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) MarkRetry(ctx context.Context, id string, leaseToken *string, attempts int, nextAttemptAt time.Time, lastError string) error {
+	args := m.Called(id, leaseToken, attempts, nextAttemptAt, lastError)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) MarkFailed(ctx context.Context, id string, leaseToken *string, attempts int, lastError string) error {
+	args := m.Called(id, leaseToken, attempts, lastError)
+	return args.Error(0)
+}
+
+var testWebhookConfig = config.WebhookConfig{
+	PollInterval:        time.Second,
+	RequestTimeout:      time.Second,
+	RetryBaseDelay:      30 * time.Second,
+	RetryMaxDelay:       time.Hour,
+	MaxAttempts:         3,
+	BatchSize:           10,
+	RenewalDaysAhead:    3,
+	RenewalScanInterval: time.Hour,
+	// Тестовые серверы слушают на loopback
+	AllowPrivateTargets: true,
+}
+
+func TestWebhookCreate_RejectsUnsafeURLs(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	cfg := testWebhookConfig
+	cfg.AllowPrivateTargets = false
+	svc := service.NewWebhookService(mockRepo, cfg)
+
+	// Только https и только публичные адреса: иначе вебхук достанет внутреннюю сеть
+	for _, url := range []string{
+		"http://203.0.113.10/hook",
+		"https://127.0.0.1/hook",
+		"https://10.0.0.5/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[::1]/hook",
+	} {
+		_, err := svc.Create(context.Background(), &model.CreateWebhookRequest{
+			URL:        url,
+			EventTypes: []string{model.EventSubscriptionCreated},
+		})
+
+		var validationErr *service.ValidationError
+		assert.ErrorAs(t, err, &validationErr, url)
+	}
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestWebhookPublish_EnqueuesForEachSubscriber(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	svc := service.NewWebhookService(mockRepo, testWebhookConfig)
+
+	webhooks := []model.Webhook{{ID: "hook-1"}, {ID: "hook-2"}}
+	mockRepo.On("ListByEventType", "brand-a", model.EventSubscriptionCreated).Return(webhooks, nil)
+	mockRepo.On("EnqueueDelivery", mock.MatchedBy(func(d *model.WebhookDelivery) bool {
+		var event model.WebhookEvent
+		if err := json.Unmarshal(d.Payload, &event); err != nil {
+			return false
+		}
+		return event.Type == model.EventSubscriptionCreated && d.DedupeKey == nil
+	})).Return(nil).Twice()
+
+	ctx := tenant.WithID(context.Background(), "brand-a")
+	err := svc.Publish(ctx, model.EventSubscriptionCreated, &model.Subscription{ID: "sub-1"})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWebhookPublish_RequiresTenant(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	svc := service.NewWebhookService(mockRepo, testWebhookConfig)
+
+	// Без арендатора событие не рассылается ничьим вебхукам
+	err := svc.Publish(tenant.AllTenants(context.Background()), model.EventSubscriptionCreated, &model.Subscription{ID: "sub-1"})
+
+	assert.ErrorIs(t, err, repository.ErrNoTenant)
+	mockRepo.AssertNotCalled(t, "ListByEventType", mock.Anything, mock.Anything)
+}
+
+func TestWebhookPublishRenewalsDue_UsesDedupeKey(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	svc := service.NewWebhookService(mockRepo, testWebhookConfig)
+
+	// Продления рассылаются только вебхукам арендатора подписки
+	mockRepo.On("ListByEventType", "brand-a", model.EventSubscriptionRenewalDue).Return([]model.Webhook{{ID: "hook-1"}}, nil)
+	mockRepo.On("ListByEventType", "brand-b", model.EventSubscriptionRenewalDue).Return([]model.Webhook{}, nil)
+	mockRepo.On("EnqueueDelivery", mock.MatchedBy(func(d *model.WebhookDelivery) bool {
+		return d.DedupeKey != nil && *d.DedupeKey == "subscription.renewal_due:sub-1:2024-05-01:hook-1"
+	})).Return(nil).Once()
+
+	renewals := []model.Renewal{
+		{SubscriptionID: "sub-1", TenantID: "brand-a", ChargeDate: "2024-05-01", Amount: 1000},
+		{SubscriptionID: "sub-2", TenantID: "brand-b", ChargeDate: "2024-05-01", Amount: 500},
+	}
+	err := svc.PublishRenewalsDue(tenant.AllTenants(context.Background()), renewals, 3)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+var testLeaseToken = "3f0c7e5a-8d1b-4c2e-9a6f-1b2c3d4e5f60"
+
+func TestWebhookDispatcher_DeliversSignedPayload(t *testing.T) {
+	secret := "0123456789abcdef"
+	payload := []byte(`{"id":"event-1","type":"subscription.created"}`)
+
+	var signatureValid bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get("X-Webhook-Timestamp"), 10, 64)
+		signatureValid = r.Header.Get("X-Webhook-Signature") == service.SignWebhookPayload(secret, timestamp, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	mockRepo := new(MockWebhookRepository)
+	dispatcher := service.NewWebhookDispatcher(mockRepo, nil, nil, testWebhookConfig)
+
+	delivery := model.WebhookDelivery{ID: "delivery-1", URL: server.URL, Secret: secret, Payload: payload, LeaseToken: &testLeaseToken}
+	mockRepo.On("ClaimDueDeliveries", 10, mock.Anything).Return([]model.WebhookDelivery{delivery}, nil)
+	mockRepo.On("HoldsLease", "delivery-1", &testLeaseToken).Return(true, nil)
+	mockRepo.On("MarkDelivered", "delivery-1", &testLeaseToken, 1).Return(nil)
+
+	delivered, err := dispatcher.DispatchPending(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.True(t, signatureValid)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWebhookDispatcher_SchedulesRetryWithBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	mockRepo := new(MockWebhookRepository)
+	dispatcher := service.NewWebhookDispatcher(mockRepo, nil, nil, testWebhookConfig)
+
+	// Вторая неудачная попытка: задержка удваивается до 60 секунд
+	delivery := model.WebhookDelivery{ID: "delivery-1", URL: server.URL, Attempts: 1, Payload: []byte(`{}`), LeaseToken: &testLeaseToken}
+	mockRepo.On("ClaimDueDeliveries", 10, mock.Anything).Return([]model.WebhookDelivery{delivery}, nil)
+	mockRepo.On("HoldsLease", "delivery-1", &testLeaseToken).Return(true, nil)
+	mockRepo.On("MarkRetry", "delivery-1", &testLeaseToken, 2, mock.MatchedBy(func(next time.Time) bool {
+		delay := time.Until(next)
+		return delay > 55*time.Second && delay <= 60*time.Second
+	}), mock.Anything).Return(nil)
+
+	delivered, err := dispatcher.DispatchPending(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWebhookDispatcher_GivesUpAfterMaxAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	mockRepo := new(MockWebhookRepository)
+	dispatcher := service.NewWebhookDispatcher(mockRepo, nil, nil, testWebhookConfig)
+
+	delivery := model.WebhookDelivery{ID: "delivery-1", URL: server.URL, Attempts: 2, Payload: []byte(`{}`), LeaseToken: &testLeaseToken}
+	mockRepo.On("ClaimDueDeliveries", 10, mock.Anything).Return([]model.WebhookDelivery{delivery}, nil)
+	mockRepo.On("HoldsLease", "delivery-1", &testLeaseToken).Return(true, nil)
+	mockRepo.On("MarkFailed", "delivery-1", &testLeaseToken, 3, mock.Anything).Return(nil)
+
+	_, err := dispatcher.DispatchPending(context.Background())
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWebhookDispatcher_SkipsDeliveriesWithLostLease(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	mockRepo := new(MockWebhookRepository)
+	dispatcher := service.NewWebhookDispatcher(mockRepo, nil, nil, testWebhookConfig)
+
+	// Аренда рассчитана на таймаут каждого запроса пачки
+	batchTimeout := time.Duration(testWebhookConfig.BatchSize) * testWebhookConfig.RequestTimeout
+	delivery := model.WebhookDelivery{ID: "delivery-1", URL: server.URL, Payload: []byte(`{}`), LeaseToken: &testLeaseToken}
+	mockRepo.On("ClaimDueDeliveries", 10, mock.MatchedBy(func(lease time.Duration) bool {
+		return lease > batchTimeout
+	})).Return([]model.WebhookDelivery{delivery}, nil)
+	// Пока доставка ждала очереди, её забрал другой диспетчер
+	mockRepo.On("HoldsLease", "delivery-1", &testLeaseToken).Return(false, nil)
+
+	delivered, err := dispatcher.DispatchPending(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	assert.Equal(t, 0, requests)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "MarkDelivered", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWebhookDispatcher_RefusesPrivateTargets(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	mockRepo := new(MockWebhookRepository)
+	cfg := testWebhookConfig
+	cfg.AllowPrivateTargets = false
+	dispatcher := service.NewWebhookDispatcher(mockRepo, nil, nil, cfg)
+
+	// Адрес проверяется при соединении, даже если вебхук был сохранён раньше
+	delivery := model.WebhookDelivery{ID: "delivery-1", URL: server.URL, Payload: []byte(`{}`), LeaseToken: &testLeaseToken}
+	mockRepo.On("ClaimDueDeliveries", 10, mock.Anything).Return([]model.WebhookDelivery{delivery}, nil)
+	mockRepo.On("HoldsLease", "delivery-1", &testLeaseToken).Return(true, nil)
+	mockRepo.On("MarkRetry", "delivery-1", &testLeaseToken, 1, mock.Anything, mock.Anything).Return(nil)
+
+	delivered, err := dispatcher.DispatchPending(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	assert.Equal(t, 0, requests)
+	mockRepo.AssertExpectations(t)
+}